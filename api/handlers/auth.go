@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
@@ -13,6 +15,13 @@ type UserService interface {
 	CreateUser(req *services.CreateUserRequest) (*models.AppUser, error)
 	ValidateCredentials(email, password string) (*models.AppUser, error)
 	GenerateToken(user *models.AppUser) (string, error)
+	CreateRefreshToken(user *models.AppUser) (string, error)
+	RefreshTokens(refreshToken string) (*services.TokenPair, error)
+	Logout(userID uint, refreshToken, jti string, expiresAt time.Time) error
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type AuthHandler struct {
@@ -66,6 +75,56 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Return the token
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	refreshToken, err := h.userService.CreateRefreshToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	// Return the tokens
+	c.JSON(http.StatusOK, services.TokenPair{AccessToken: token, RefreshToken: refreshToken})
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	tokens, err := h.userService.RefreshTokens(req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout must run behind AuthMiddleware, which provides the access token's
+// jti and expiry. The refresh token in the body is optional.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req refreshTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err := h.userService.Logout(c.GetUint("user_id"), req.RefreshToken, c.GetString("jti"), c.GetTime("token_expires_at"))
+	if errors.Is(err, services.ErrInvalidToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
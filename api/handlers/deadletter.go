@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterService interface {
+	ListDeadLetters(filter services.DeadLetterFilter, limit int) ([]messaging.DeadLetter, error)
+	GetDeadLetter(id string) (*messaging.DeadLetter, error)
+	PurgeDeadLetters(filter services.DeadLetterFilter) (int, error)
+	ReplayDeadLetters(filter services.DeadLetterFilter) ([]messaging.DeadLetter, error)
+}
+
+type DeadLetterHandler struct {
+	service DeadLetterService
+}
+
+func NewDeadLetterHandler(service DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{service: service}
+}
+
+const defaultDeadLetterLimit = 100
+
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	var filter services.DeadLetterFilter
+	productID, err := strconv.ParseUint(c.DefaultQuery("product_id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product_id"})
+		return
+	}
+	filter.ProductID = uint(productID)
+	filter.ErrorClass = c.Query("error_class")
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit == 0 {
+		limit = defaultDeadLetterLimit
+	}
+
+	messages, err := h.service.ListDeadLetters(filter, limit)
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	if messages == nil {
+		messages = []messaging.DeadLetter{}
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	message, err := h.service.GetDeadLetter(c.Param("id"))
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	replayed, err := h.service.ReplayDeadLetters(services.DeadLetterFilter{IDs: []string{c.Param("id")}})
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	if len(replayed) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found or not replayable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, replayed[0])
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	purged, err := h.service.PurgeDeadLetters(services.DeadLetterFilter{IDs: []string{c.Param("id")}})
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	if purged == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetters(c *gin.Context) {
+	var filter services.DeadLetterFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	replayed, err := h.service.ReplayDeadLetters(filter)
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	if replayed == nil {
+		replayed = []messaging.DeadLetter{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+func (h *DeadLetterHandler) PurgeDeadLetters(c *gin.Context) {
+	var filter services.DeadLetterFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purged, err := h.service.PurgeDeadLetters(filter)
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func respondDeadLetterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ProcessingEventService interface {
+	LastProcessingEvent(ctx context.Context, productID uint) (string, error)
+	ReadProcessingEvents(ctx context.Context, productID uint, after string, wait time.Duration) ([]services.ProcessingEvent, error)
+}
+
+// EventsConfig tunes event streams. Zero values fall back to the defaults
+// below.
+type EventsConfig struct {
+	// Heartbeat is the longest a stream stays silent; idle streams send a
+	// comment so proxies don't close them.
+	Heartbeat time.Duration
+	// MaxStreams is the number of streams open at once. Further clients
+	// are turned away until one closes.
+	MaxStreams int
+}
+
+const (
+	defaultHeartbeat  = 15 * time.Second
+	defaultMaxStreams = 100
+)
+
+type ProductEventsHandler struct {
+	productService ProductService
+	events         ProcessingEventService
+	cfg            EventsConfig
+	// streams holds a slot per open stream.
+	streams chan struct{}
+
+	// closing ends open streams when the server shuts down, which would
+	// otherwise wait for them until its deadline.
+	closing      context.Context
+	closeStreams context.CancelFunc
+}
+
+func NewProductEventsHandler(productService ProductService, events ProcessingEventService, cfg EventsConfig) *ProductEventsHandler {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = defaultHeartbeat
+	}
+	if cfg.MaxStreams <= 0 {
+		cfg.MaxStreams = defaultMaxStreams
+	}
+
+	closing, closeStreams := context.WithCancel(context.Background())
+	return &ProductEventsHandler{
+		productService: productService,
+		events:         events,
+		cfg:            cfg,
+		streams:        make(chan struct{}, cfg.MaxStreams),
+		closing:        closing,
+		closeStreams:   closeStreams,
+	}
+}
+
+// Close ends every open stream.
+func (h *ProductEventsHandler) Close() {
+	h.closeStreams()
+}
+
+// eventIDPattern matches the IDs given to events, "<milliseconds>-<sequence>".
+var eventIDPattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?$`)
+
+// StreamProductEvents streams the processing status of a product as
+// server-sent events. A new stream starts with the current status; a client
+// reconnecting with Last-Event-ID receives the events it missed instead.
+func (h *ProductEventsHandler) StreamProductEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	select {
+	case h.streams <- struct{}{}:
+		defer func() { <-h.streams }()
+	default:
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many open event streams"})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	stop := context.AfterFunc(h.closing, cancel)
+	defer stop()
+
+	// Take the position in the stream before loading the product so no
+	// change between the two is missed
+	lastID := c.GetHeader("Last-Event-ID")
+	resume := lastID != ""
+	if resume && !eventIDPattern.MatchString(lastID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+		return
+	}
+	if !resume {
+		lastID, err = h.events.LastProcessingEvent(ctx, uint(id))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	product, err := h.productService.GetProduct(currentActor(c), uint(id))
+	if err != nil {
+		respondProductError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !resume {
+		writeEvent(c.Writer, services.ProcessingEvent{
+			ID:               lastID,
+			ProductID:        product.ID,
+			ProcessingStatus: product.ProcessingStatus,
+			Images:           product.ImageVariants,
+			At:               product.UpdatedAt,
+		})
+	} else {
+		io.WriteString(c.Writer, ": resumed\n\n")
+	}
+	c.Writer.Flush()
+
+	for {
+		events, err := h.events.ReadProcessingEvents(ctx, uint(id), lastID, h.cfg.Heartbeat)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to read events of product %d: %v", id, err)
+			return
+		}
+
+		if len(events) == 0 {
+			io.WriteString(c.Writer, ": heartbeat\n\n")
+		}
+		for _, event := range events {
+			writeEvent(c.Writer, event)
+			lastID = event.ID
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, event services.ProcessingEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %s\nevent: processing_status\ndata: %s\n\n", event.ID, data)
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
@@ -11,18 +16,44 @@ import (
 
 type ProductHandler struct {
 	productService ProductService
+	uploads        UploadConfig
 }
 
+// UploadConfig limits multipart image uploads. Zero values fall back to the
+// defaults below.
+type UploadConfig struct {
+	MaxImageBytes int64
+	MaxImages     int
+}
+
+const (
+	defaultMaxImageBytes = 10 << 20
+	defaultMaxImages     = 10
+)
+
 type ProductService interface {
-	CreateProduct(req *services.CreateProductRequest) (*models.Product, error)
-	GetProduct(id uint) (*models.Product, error)
-	GetUserProducts(userID uint) ([]models.Product, error)
-	GetFilteredProducts(req *services.FilterProductsRequest) ([]models.Product, error)
+	CreateProduct(actor services.Actor, req *services.CreateProductRequest) (*models.Product, error)
+	GetProduct(actor services.Actor, id uint) (*models.Product, error)
+	GetProducts(actor services.Actor, ids []uint) ([]services.ProductLookup, error)
+	GetFilteredProducts(actor services.Actor, req *services.FilterProductsRequest) (*services.ProductPage, error)
+	ReplaceProduct(actor services.Actor, id uint, req *services.UpdateProductRequest) (*models.Product, error)
+	PatchProduct(actor services.Actor, id uint, patch []byte) (*models.Product, error)
+	DeleteProduct(actor services.Actor, id uint) error
+	AddProductImages(actor services.Actor, id uint, images []services.UploadedImage) (*models.Product, error)
+	ReprocessFailedImages(actor services.Actor, id uint) (*models.Product, error)
 }
 
-func NewProductHandler(service ProductService) *ProductHandler {
+func NewProductHandler(service ProductService, uploads UploadConfig) *ProductHandler {
+	if uploads.MaxImageBytes == 0 {
+		uploads.MaxImageBytes = defaultMaxImageBytes
+	}
+	if uploads.MaxImages == 0 {
+		uploads.MaxImages = defaultMaxImages
+	}
+
 	return &ProductHandler{
 		productService: service,
+		uploads:        uploads,
 	}
 }
 
@@ -33,9 +64,9 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.CreateProduct(&req)
+	product, err := h.productService.CreateProduct(currentActor(c), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProductError(c, err)
 		return
 	}
 
@@ -49,7 +80,11 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.GetProduct(uint(id))
+	product, err := h.productService.GetProduct(currentActor(c), uint(id))
+	if errors.Is(err, services.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
 		return
@@ -58,24 +93,74 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, product)
 }
 
-func (h *ProductHandler) GetUserProducts(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+// BatchProductsRequest lists the product IDs to look up at once.
+type BatchProductsRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// GetProductsBatch looks up many products in one request. Each ID gets its
+// own status, so missing or forbidden products don't fail the request.
+func (h *ProductHandler) GetProductsBatch(c *gin.Context) {
+	var req BatchProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.productService.GetProducts(currentActor(c), req.IDs)
+	if err != nil {
+		respondProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// GetProductImage redirects to the smallest file of an image variant in a
+// format the client's Accept header allows.
+func (h *ProductHandler) GetProductImage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image index"})
 		return
 	}
 
-	products, err := h.productService.GetUserProducts(uint(userID))
+	product, err := h.productService.GetProduct(currentActor(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProductError(c, err)
+		return
+	}
+
+	if index >= len(product.ImageVariants) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
 		return
 	}
+	for _, variant := range product.ImageVariants[index].Variants {
+		if variant.Name == c.Param("variant") {
+			file := variant.Smallest(acceptsContentType(c.GetHeader("Accept")))
+			c.Header("Vary", "Accept")
+			c.Redirect(http.StatusFound, file.URL)
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "image variant not found"})
+}
 
-	c.JSON(http.StatusOK, products)
+// GetUserProducts lists the caller's products. It accepts the same query
+// parameters as GetFilteredProducts.
+func (h *ProductHandler) GetUserProducts(c *gin.Context) {
+	h.GetFilteredProducts(c)
 }
 
 func (h *ProductHandler) GetFilteredProducts(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	actor := currentActor(c)
+	userID, err := queryUserID(c, actor)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
 		return
@@ -85,18 +170,270 @@ func (h *ProductHandler) GetFilteredProducts(c *gin.Context) {
 	maxPrice, _ := strconv.ParseFloat(c.Query("max_price"), 64)
 	productName := c.Query("product_name")
 
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
 	req := services.FilterProductsRequest{
-		UserID:      uint(userID),
+		UserID:      userID,
 		MinPrice:    minPrice,
 		MaxPrice:    maxPrice,
 		ProductName: productName,
+		Sort:        c.Query("sort"),
+		Limit:       limit,
+		Offset:      offset,
+		Cursor:      c.Query("cursor"),
 	}
 
-	products, err := h.productService.GetFilteredProducts(&req)
+	page, err := h.productService.GetFilteredProducts(actor, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var req services.UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.ReplaceProduct(currentActor(c), uint(id), &req)
+	if err != nil {
+		respondProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.productService.PatchProduct(currentActor(c), uint(id), patch)
+	if err != nil {
+		respondProductError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	if err := h.productService.DeleteProduct(currentActor(c), uint(id)); err != nil {
+		respondProductError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UploadProductImages accepts one or more files in the "images" field of a
+// multipart form and adds them to the product.
+func (h *ProductHandler) UploadProductImages(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	// Leave headroom for the multipart framing around the files
+	maxBody := h.uploads.MaxImageBytes*int64(h.uploads.MaxImages) + 1<<20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "upload too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	files := form.File["images"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no images uploaded"})
+		return
+	}
+	if len(files) > h.uploads.MaxImages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d images per upload", h.uploads.MaxImages)})
+		return
+	}
+
+	images := make([]services.UploadedImage, 0, len(files))
+	for _, file := range files {
+		if file.Size > h.uploads.MaxImageBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%s exceeds %d bytes", file.Filename, h.uploads.MaxImageBytes)})
+			return
+		}
+
+		data, err := readFormFile(file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		images = append(images, services.UploadedImage{Filename: file.Filename, Data: data})
+	}
+
+	product, err := h.productService.AddProductImages(currentActor(c), uint(id), images)
+	if err != nil {
+		respondProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// ReprocessProductImages queues processing again for the product's failed
+// images.
+func (h *ProductHandler) ReprocessProductImages(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	product, err := h.productService.ReprocessFailedImages(currentActor(c), uint(id))
+	if err != nil {
+		respondProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, product)
+}
+
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func respondProductError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidProduct), errors.Is(err, services.ErrInvalidFilter),
+		errors.Is(err, services.ErrInvalidImage), errors.Is(err, services.ErrInvalidBatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	case errors.Is(err, services.ErrNoFailedImages):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// acceptsContentType reports whether an Accept header allows a media type.
+// The most specific matching range decides, q=0 refuses the type, other
+// parameters are ignored and an empty header accepts anything.
+func acceptsContentType(accept string) func(contentType string) bool {
+	return func(contentType string) bool {
+		if strings.TrimSpace(accept) == "" {
+			return true
+		}
+		major, _, _ := strings.Cut(contentType, "/")
+
+		specificity, accepted := -1, false
+		for _, mediaRange := range strings.Split(accept, ",") {
+			params := strings.Split(mediaRange, ";")
+
+			var rank int
+			switch strings.ToLower(strings.TrimSpace(params[0])) {
+			case contentType:
+				rank = 2
+			case major + "/*":
+				rank = 1
+			case "*/*":
+				rank = 0
+			default:
+				continue
+			}
+			if rank < specificity {
+				continue
+			}
+
+			q := 1.0
+			for _, param := range params[1:] {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if key == "q" {
+					if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+			specificity, accepted = rank, q > 0
+		}
+		return accepted
+	}
+}
+
+// currentActor builds the service principal from the claims AuthMiddleware
+// stored in the context.
+func currentActor(c *gin.Context) services.Actor {
+	return services.Actor{
+		UserID: c.GetUint("user_id"),
+		Admin:  c.GetString("role") == models.RoleAdmin,
+	}
+}
+
+// queryUserID reads the optional user_id query parameter, defaulting to the
+// caller. The service decides whether the caller may see that user's data.
+func queryUserID(c *gin.Context, actor services.Actor) (uint, error) {
+	raw := c.Query("user_id")
+	if raw == "" {
+		return actor.UserID, nil
+	}
+	userID, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// queryInt reads an optional integer query parameter, returning 0 when absent.
+func queryInt(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(raw)
 }
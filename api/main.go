@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/config"
 	"github.com/KPVISHNUSAI/product-management-system/api/handlers"
 	"github.com/KPVISHNUSAI/product-management-system/api/middleware"
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/api/repository/postgres"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/database"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/storage"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -27,12 +35,33 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	// Initialize RabbitMQ
-	mqClient, err := messaging.NewRabbitMQClient(cfg.RabbitMQ.URL)
-	if err != nil {
-		logger.Fatal("failed to connect to RabbitMQ", zap.Error(err))
+	// Initialize the message broker
+	var broker interface {
+		messaging.Publisher
+		Close() error
+	}
+	var deadLetters services.DeadLetterQueue
+	switch cfg.Broker.Backend {
+	case messaging.BrokerRabbitMQ, "":
+		mqClient, err := messaging.NewRabbitMQClient(cfg.RabbitMQ.URL, messaging.Config{
+			Topology:        messaging.ImageProcessingTopology(nil),
+			ConfirmTimeout:  cfg.RabbitMQ.ConfirmTimeout,
+			ChannelPoolSize: cfg.RabbitMQ.ChannelPoolSize,
+		})
+		if err != nil {
+			logger.Fatal("failed to connect to RabbitMQ", zap.Error(err))
+		}
+		broker = mqClient
+		deadLetters = messaging.NewDeadLetterQueue(mqClient, messaging.ImageProcessingDLQ, messaging.ImageProcessingQueue)
+	case messaging.BrokerMemory:
+		// Tasks stay in this process, so no image processor sees them
+		logger.Warn("using the in-memory broker, queued tasks are not processed and are lost on exit")
+		memoryBroker := messaging.NewMemoryBroker(messaging.ImageProcessingTopology(nil))
+		broker = memoryBroker
+		deadLetters = memoryBroker.DeadLetterQueue(messaging.ImageProcessingDLQ, messaging.ImageProcessingQueue)
+	default:
+		logger.Fatal("unknown broker backend", zap.String("backend", cfg.Broker.Backend))
 	}
-	defer mqClient.Close()
 
 	// Initialize database
 	db, err := database.NewPostgresDB(
@@ -47,35 +76,134 @@ func main() {
 	}
 
 	// Initialize Redis
-	redisAddr := fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port)
-	logger.Info("Connecting to Redis", zap.String("addr", redisAddr)) // Add logging
-	redisClient, err := cache.NewRedisCache(redisAddr, cfg.Redis.Password)
+	logger.Info("Connecting to Redis", zap.String("mode", cfg.Redis.Mode), zap.Strings("addrs", cfg.Redis.Addrs))
+	redisConfig := cache.RedisConfig{
+		Mode:             cfg.Redis.Mode,
+		Addrs:            cfg.Redis.Addrs,
+		MasterName:       cfg.Redis.MasterName,
+		Username:         cfg.Redis.Username,
+		Password:         cfg.Redis.Password,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		DB:               cfg.Redis.DB,
+		TLS:              cfg.Redis.TLS,
+		PoolSize:         cfg.Redis.PoolSize,
+		MinIdleConns:     cfg.Redis.MinIdleConns,
+		DialTimeout:      cfg.Redis.DialTimeout,
+		ReadTimeout:      cfg.Redis.ReadTimeout,
+		WriteTimeout:     cfg.Redis.WriteTimeout,
+	}
+	redisClient, err := cache.NewRedisCache(redisConfig)
 	if err != nil {
 		logger.Fatal("failed to connect to Redis",
 			zap.Error(err),
-			zap.String("addr", redisAddr),
-			zap.String("host", cfg.Redis.Host),
-			zap.String("port", cfg.Redis.Port))
+			zap.String("mode", cfg.Redis.Mode),
+			zap.Strings("addrs", cfg.Redis.Addrs))
+	}
+
+	// Event streams block on reads, give them a pool of their own with a
+	// connection per stream
+	streamRedisConfig := redisConfig
+	streamRedisConfig.PoolSize = cfg.Events.MaxStreams
+	streamRedisConfig.MinIdleConns = 0
+	streamReaders, err := cache.NewRedisCache(streamRedisConfig)
+	if err != nil {
+		logger.Fatal("failed to connect to Redis for event streams", zap.Error(err))
+	}
+
+	// Keep hot entries in process, in front of Redis
+	productCache, err := cache.NewTieredCache(redisClient, cache.TieredConfig{
+		Size: cfg.Cache.LocalSize,
+		TTL:  cfg.Cache.LocalTTL,
+	})
+	if err != nil {
+		logger.Fatal("failed to subscribe to cache invalidations", zap.Error(err))
+	}
+
+	// Initialize image storage
+	imageStore, err := storage.New(storage.Config{
+		Backend: cfg.Storage.Backend,
+		S3: storage.S3Config{
+			Region:         cfg.AWS.Region,
+			Bucket:         cfg.AWS.Bucket,
+			AccessKey:      cfg.AWS.AccessKey,
+			SecretKey:      cfg.AWS.SecretKey,
+			Endpoint:       cfg.AWS.Endpoint,
+			PublicURL:      cfg.AWS.PublicURL,
+			ForcePathStyle: cfg.AWS.ForcePathStyle,
+		},
+		Local: storage.LocalConfig{
+			Root:      cfg.Storage.LocalRoot,
+			PublicURL: cfg.Storage.PublicURL,
+		},
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize image storage", zap.Error(err))
 	}
 
 	// Initialize repositories
 	userRepo := postgres.NewUserRepository(db)
 	productRepo := postgres.NewProductRepository(db)
+	tokenRepo := postgres.NewRefreshTokenRepository(db)
+	webhookRepo := postgres.NewWebhookRepository(db)
+	outboxRepo := postgres.NewOutboxRepository(db)
+	denylist := cache.NewTokenDenylist(redisClient)
 
 	// Initialize services
-	userService := services.NewUserService(userRepo, cfg.Server.JWTSecret)
-	// Initialize services with MQ
-	productService := services.NewProductService(productRepo, mqClient, redisClient)
+	userService := services.NewUserService(userRepo, tokenRepo, denylist, services.TokenConfig{
+		Secret:     cfg.Server.JWTSecret,
+		AccessTTL:  cfg.Server.AccessTokenTTL,
+		RefreshTTL: cfg.Server.RefreshTokenTTL,
+	})
+	webhookService := services.NewWebhookService(webhookRepo, services.WebhookConfig{
+		MaxAttempts:         cfg.Webhooks.MaxAttempts,
+		Timeout:             cfg.Webhooks.Timeout,
+		PollInterval:        cfg.Webhooks.PollInterval,
+		AllowPrivateTargets: cfg.Webhooks.AllowPrivateTargets,
+	})
+	productService := services.NewProductService(productRepo, productCache, imageStore, webhookService, services.ProductCacheConfig{
+		Jitter:               cfg.Cache.TTLJitter,
+		StaleWhileRevalidate: cfg.Cache.StaleWhileRevalidate,
+		NotFoundTTL:          cfg.Cache.NotFoundTTL,
+	})
+	outboxRelay := services.NewOutboxRelay(outboxRepo, broker, services.OutboxConfig{
+		PollInterval: cfg.Outbox.PollInterval,
+		Retention:    cfg.Outbox.Retention,
+	})
+
+	eventService := services.NewProcessingEventService(cache.NewEventStream(redisClient, cache.StreamConfig{
+		Readers: streamReaders,
+	}))
+
+	deadLetterService := services.NewDeadLetterService(
+		deadLetters,
+		productRepo,
+		productService,
+	)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(userService)
-	productHandler := handlers.NewProductHandler(productService)
+	productHandler := handlers.NewProductHandler(productService, handlers.UploadConfig{
+		MaxImageBytes: cfg.Upload.MaxImageBytes,
+		MaxImages:     cfg.Upload.MaxImages,
+	})
+	eventsHandler := handlers.NewProductEventsHandler(productService, eventService, handlers.EventsConfig{
+		Heartbeat:  cfg.Events.Heartbeat,
+		MaxStreams: cfg.Events.MaxStreams,
+	})
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	adminHandler := handlers.NewAdminHandler(userService)
+	deadLetterHandler := handlers.NewDeadLetterHandler(deadLetterService)
 
 	// Initialize router
 	r := gin.New()
 	r.Use(gin.Recovery())
 	r.Use(middleware.LoggingMiddleware(logger))
 
+	// Serve locally stored images when running without S3
+	if cfg.Storage.Backend == storage.BackendLocal {
+		r.Static("/files", cfg.Storage.LocalRoot)
+	}
+
 	// Routes
 	api := r.Group("/api")
 	{
@@ -84,20 +212,138 @@ func main() {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", middleware.AuthMiddleware(cfg.Server.JWTSecret, denylist), authHandler.Logout)
 		}
 
 		// Protected routes
 		products := api.Group("/products")
-		products.Use(middleware.AuthMiddleware(cfg.Server.JWTSecret))
+		products.Use(middleware.AuthMiddleware(cfg.Server.JWTSecret, denylist))
 		{
-			products.POST("/", productHandler.CreateProduct)
-			products.GET("/:id", productHandler.GetProduct)
-			products.GET("/filter", productHandler.GetFilteredProducts)
+			canRead := middleware.RequirePermission(models.PermissionReadProducts)
+			canWrite := middleware.RequirePermission(models.PermissionWriteProducts)
+
+			products.POST("/", canWrite, productHandler.CreateProduct)
+			products.GET("/:id", canRead, productHandler.GetProduct)
+			products.POST("/batch", canRead, productHandler.GetProductsBatch)
+			products.GET("/filter", canRead, productHandler.GetFilteredProducts)
+			products.PUT("/:id", canWrite, productHandler.UpdateProduct)
+			products.PATCH("/:id", canWrite, productHandler.PatchProduct)
+			products.DELETE("/:id", canWrite, productHandler.DeleteProduct)
+			products.POST("/:id/images", canWrite, productHandler.UploadProductImages)
+			products.POST("/:id/images/reprocess", canWrite, productHandler.ReprocessProductImages)
+			products.GET("/:id/images/:index/:variant", canRead, productHandler.GetProductImage)
+			products.GET("/:id/events", canRead, eventsHandler.StreamProductEvents)
+		}
+
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(middleware.AuthMiddleware(cfg.Server.JWTSecret, denylist))
+		webhooks.Use(middleware.RequirePermission(models.PermissionWriteProducts))
+		{
+			webhooks.POST("/", webhookHandler.CreateWebhook)
+			webhooks.GET("/", webhookHandler.ListWebhooks)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+		}
+
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(cfg.Server.JWTSecret, denylist))
+		admin.Use(middleware.RequireRole(models.RoleAdmin))
+		{
+			admin.GET("/users", adminHandler.ListUsers)
+			admin.PUT("/users/:id/role", adminHandler.AssignRole)
+
+			admin.GET("/dlq", deadLetterHandler.ListDeadLetters)
+			admin.GET("/dlq/:id", deadLetterHandler.GetDeadLetter)
+			admin.POST("/dlq/:id/replay", deadLetterHandler.ReplayDeadLetter)
+			admin.DELETE("/dlq/:id", deadLetterHandler.DeleteDeadLetter)
+			admin.POST("/dlq/replay", deadLetterHandler.ReplayDeadLetters)
+			admin.POST("/dlq/purge", deadLetterHandler.PurgeDeadLetters)
 		}
 	}
 
 	// Start server
-	if err := r.Run(":" + cfg.Server.Port); err != nil {
-		logger.Fatal("failed to start server", zap.Error(err))
+	srv := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: r,
+	}
+	// Event streams never finish on their own, end them when shutdown starts
+	srv.RegisterOnShutdown(eventsHandler.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Deliver webhooks in the background until shutdown
+	webhooksDone := make(chan struct{})
+	go func() {
+		defer close(webhooksDone)
+		webhookService.Run(ctx)
+	}()
+
+	// Publish queued tasks from the outbox until shutdown
+	outboxDone := make(chan struct{})
+	go func() {
+		defer close(outboxDone)
+		outboxRelay.Run(ctx)
+	}()
+
+	// Expose cache metrics at /debug/vars
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		metricsServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("metrics server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("failed to start server", zap.Error(err))
+		}
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Stop accepting connections and let in-flight requests finish
+	logger.Info("shutting down", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("forced shutdown with requests in flight", zap.Error(err))
+	}
+	<-webhooksDone
+	<-outboxDone
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+
+	// Close clients in dependency order
+	if err := database.Close(db); err != nil {
+		logger.Error("failed to close database", zap.Error(err))
+	}
+	if err := productCache.Close(); err != nil {
+		logger.Error("failed to close cache", zap.Error(err))
+	}
+	if err := streamReaders.Close(); err != nil {
+		logger.Error("failed to close Redis for event streams", zap.Error(err))
+	}
+	if err := redisClient.Close(); err != nil {
+		logger.Error("failed to close Redis", zap.Error(err))
+	}
+	if err := broker.Close(); err != nil {
+		logger.Error("failed to close broker", zap.Error(err))
 	}
 }
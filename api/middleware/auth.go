@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -43,7 +45,15 @@ import (
 // 	}
 // }
 
-func AuthMiddleware(secretKey string) gin.HandlerFunc {
+// RevocationChecker reports whether an access token ID has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// AuthMiddleware validates the bearer token and stores its claims in the
+// context. Tokens whose jti is on the denylist are rejected; a nil denylist
+// disables the check.
+func AuthMiddleware(secretKey string, denylist RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -64,11 +74,32 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("user_id", uint(claims["user_id"].(float64)))
-			c.Next()
-		} else {
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			return
+		}
+
+		jti, _ := claims["jti"].(string)
+		if jti != "" && denylist != nil {
+			revoked, err := denylist.IsRevoked(c.Request.Context(), jti)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "unable to verify token"})
+				return
+			}
+			if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+				return
+			}
+		}
+
+		role, _ := claims["role"].(string)
+		c.Set("user_id", uint(claims["user_id"].(float64)))
+		c.Set("role", role)
+		c.Set("jti", jti)
+		if exp, ok := claims["exp"].(float64); ok {
+			c.Set("token_expires_at", time.Unix(int64(exp), 0))
 		}
+		c.Next()
 	}
 }
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ImageVariant is one resized rendition of a source image. URL and Format
+// name the primary file, Formats lists it together with the same image
+// encoded in every other configured format.
+type ImageVariant struct {
+	Name    string      `json:"name"`
+	URL     string      `json:"url"`
+	Width   int         `json:"width"`
+	Height  int         `json:"height"`
+	Format  string      `json:"format"`
+	Formats []ImageFile `json:"formats,omitempty"`
+}
+
+// ImageFile is a variant encoded in one format.
+type ImageFile struct {
+	Format string `json:"format"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+}
+
+// ContentType returns the media type of the file.
+func (f ImageFile) ContentType() string {
+	return "image/" + f.Format
+}
+
+// Smallest returns the smallest file whose format accept allows, falling
+// back to the primary file.
+func (v ImageVariant) Smallest(accept func(contentType string) bool) ImageFile {
+	best := ImageFile{Format: v.Format, URL: v.URL}
+	found := false
+	for _, file := range v.Formats {
+		if !accept(file.ContentType()) {
+			continue
+		}
+		if !found || file.Size < best.Size {
+			best, found = file, true
+		}
+	}
+	return best
+}
+
+// Processing states of a single source image.
+const (
+	ImageStatusPending    = "pending"
+	ImageStatusProcessing = "processing"
+	ImageStatusDone       = "done"
+	ImageStatusFailed     = "failed"
+)
+
+// ProcessedImage is the processing state of one source image and the
+// variants generated from it. Error is the error of the last failed attempt
+// and Attempts counts finished attempts.
+type ProcessedImage struct {
+	Source   string         `json:"source"`
+	Status   string         `json:"status"`
+	Error    string         `json:"error,omitempty"`
+	Attempts int            `json:"attempts"`
+	Variants []ImageVariant `json:"variants"`
+}
+
+// ImageVariants is stored as a JSONB array with one entry per source image,
+// in the order of the product's images.
+type ImageVariants []ProcessedImage
+
+// PendingImages returns a pending entry for each source.
+func PendingImages(sources []string) ImageVariants {
+	images := make(ImageVariants, 0, len(sources))
+	for _, source := range sources {
+		images = append(images, ProcessedImage{Source: source, Status: ImageStatusPending})
+	}
+	return images
+}
+
+// Sources returns the sources of the images with the given status.
+func (v ImageVariants) Sources(status string) []string {
+	var sources []string
+	for _, image := range v {
+		if image.Status == status {
+			sources = append(sources, image.Source)
+		}
+	}
+	return sources
+}
+
+// ImageResult updates the state of one source image. Attempted marks the
+// end of a processing attempt, which counts towards the image's attempts
+// and replaces its error.
+type ImageResult struct {
+	Source    string
+	Status    string
+	Error     string
+	Variants  []ImageVariant
+	Attempted bool
+}
+
+// MergeImageResults applies results to images and returns one entry per
+// source, in order. Results for images that are no longer among the sources
+// are dropped, and sources without an entry yet start out pending.
+func MergeImageResults(sources []string, images ImageVariants, results []ImageResult) ImageVariants {
+	existing := make(map[string]ProcessedImage, len(images))
+	for _, image := range images {
+		existing[image.Source] = image
+	}
+	updates := make(map[string]ImageResult, len(results))
+	for _, result := range results {
+		updates[result.Source] = result
+	}
+
+	merged := make(ImageVariants, 0, len(sources))
+	for _, source := range sources {
+		image, ok := existing[source]
+		if !ok {
+			image = ProcessedImage{Source: source, Status: ImageStatusPending}
+		}
+
+		if result, ok := updates[source]; ok {
+			image.Status = result.Status
+			if result.Status == ImageStatusDone {
+				image.Variants = result.Variants
+			}
+			if result.Attempted {
+				image.Attempts++
+				image.Error = result.Error
+			}
+		}
+		merged = append(merged, image)
+	}
+	return merged
+}
+
+func (v ImageVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (v *ImageVariants) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into ImageVariants", src)
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxMessage is a message written in the same transaction as the change
+// that caused it and published to Queue afterwards by the outbox relay.
+// SentAt is set once the broker has the message.
+type OutboxMessage struct {
+	ID            uint      `gorm:"primaryKey"`
+	Queue         string    `gorm:"not null"`
+	Payload       []byte    `gorm:"not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	LastError     string    `gorm:"not null;default:''"`
+	SentAt        *time.Time
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "app_outbox"
+}
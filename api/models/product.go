@@ -7,19 +7,99 @@ import (
 )
 
 type Product struct {
-	ID                      uint           `gorm:"primaryKey"`
-	UserID                  uint           `gorm:"not null"`
-	ProductName             string         `gorm:"not null"`
-	ProductDescription      string         `gorm:"column:product_description"`
-	ProductPrice            float64        `gorm:"not null"`
-	ProductImages           pq.StringArray `gorm:"type:text[]"`
-	CompressedProductImages pq.StringArray `gorm:"type:text[]"`
-	ProcessingStatus        string         `gorm:"default:pending"`
-	CreatedAt               time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt               time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-	User                    AppUser        `gorm:"foreignKey:UserID"`
+	ID                 uint           `gorm:"primaryKey"`
+	UserID             uint           `gorm:"not null"`
+	ProductName        string         `gorm:"not null"`
+	ProductDescription string         `gorm:"column:product_description"`
+	ProductPrice       float64        `gorm:"not null"`
+	ProductImages      pq.StringArray `gorm:"type:text[]"`
+	ImageVariants      ImageVariants  `gorm:"type:jsonb;not null;default:'[]'"`
+	ProcessingStatus   string         `gorm:"default:pending"`
+	CreatedAt          time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+	User               AppUser        `gorm:"foreignKey:UserID"`
 }
 
 func (Product) TableName() string {
 	return "app_products"
 }
+
+// Processing states of a product, summarising the states of its images.
+const (
+	ProcessingStatusPending            = "pending"
+	ProcessingStatusProcessing         = "processing"
+	ProcessingStatusCompleted          = "completed"
+	ProcessingStatusFailed             = "failed"
+	ProcessingStatusPartiallyCompleted = "partially_completed"
+)
+
+// ProcessingDone reports whether status is final, with no image of the
+// product waiting to be processed.
+func ProcessingDone(status string) bool {
+	switch status {
+	case ProcessingStatusCompleted, ProcessingStatusFailed, ProcessingStatusPartiallyCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProcessingStatusOf summarises image states into a product state. Work in
+// progress wins over waiting work; once nothing is left the product is
+// completed, failed or, with a mix of both, partially completed.
+func ProcessingStatusOf(images ImageVariants) string {
+	var pending, done, failed int
+	for _, image := range images {
+		switch image.Status {
+		case ImageStatusProcessing:
+			return ProcessingStatusProcessing
+		case ImageStatusPending:
+			pending++
+		case ImageStatusDone:
+			done++
+		case ImageStatusFailed:
+			failed++
+		}
+	}
+
+	switch {
+	case pending > 0:
+		return ProcessingStatusPending
+	case failed > 0 && done > 0:
+		return ProcessingStatusPartiallyCompleted
+	case failed > 0:
+		return ProcessingStatusFailed
+	default:
+		return ProcessingStatusCompleted
+	}
+}
+
+// ProductFilter selects one page of a user's products. Sort is one of the
+// ProductSort* fields, optionally prefixed with "-" for descending order.
+// When After is set the page starts after that row and Offset is ignored.
+type ProductFilter struct {
+	UserID      uint
+	MinPrice    float64
+	MaxPrice    float64
+	ProductName string
+	Sort        string
+	Limit       int
+	Offset      int
+	After       *ProductCursor
+}
+
+const (
+	ProductSortPrice     = "price"
+	ProductSortCreatedAt = "created_at"
+	ProductSortName      = "name"
+)
+
+// ProductCursor is the keyset position of the last product of a page. Only
+// the field matching Sort is populated, ID breaks ties.
+type ProductCursor struct {
+	Sort      string    `json:"s"`
+	ID        uint      `json:"id"`
+	Price     float64   `json:"p,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Name      string    `json:"n,omitempty"`
+}
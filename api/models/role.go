@@ -0,0 +1,33 @@
+package models
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+const (
+	PermissionReadProducts  = "products:read"
+	PermissionWriteProducts = "products:write"
+	PermissionManageUsers   = "users:manage"
+)
+
+var rolePermissions = map[string][]string{
+	RoleAdmin:  {PermissionReadProducts, PermissionWriteProducts, PermissionManageUsers},
+	RoleEditor: {PermissionReadProducts, PermissionWriteProducts},
+	RoleViewer: {PermissionReadProducts},
+}
+
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
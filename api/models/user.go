@@ -8,7 +8,8 @@ type AppUser struct {
 	ID        uint      `gorm:"primaryKey"`
 	Email     string    `gorm:"unique;not null"`
 	Name      string    `gorm:"not null"`
-	Password  string    `gorm:"not null"`
+	Password  string    `gorm:"not null" json:"-"`
+	Role      string    `gorm:"not null;default:editor"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Product lifecycle events delivered to webhooks. EventProductProcessed fires
+// when image processing of a product finishes, whatever the outcome.
+const (
+	EventProductCreated   = "product.created"
+	EventProductUpdated   = "product.updated"
+	EventProductDeleted   = "product.deleted"
+	EventProductProcessed = "product.processed"
+)
+
+var webhookEvents = map[string]bool{
+	EventProductCreated:   true,
+	EventProductUpdated:   true,
+	EventProductDeleted:   true,
+	EventProductProcessed: true,
+}
+
+func ValidWebhookEvent(eventType string) bool {
+	return webhookEvents[eventType]
+}
+
+// WebhookSubscription sends a user's events of the listed types to URL. The
+// secret signs every delivery and is only shown when the subscription is
+// created.
+type WebhookSubscription struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	URL       string         `gorm:"not null" json:"url"`
+	Events    pq.StringArray `gorm:"type:text[];not null" json:"events"`
+	Secret    string         `gorm:"not null" json:"-"`
+	Active    bool           `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "app_webhook_subscriptions"
+}
+
+// States of a webhook delivery. Pending deliveries are sent, or retried, once
+// NextAttemptAt has passed.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscription, together with the
+// outcome of the latest attempt.
+type WebhookDelivery struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint                `gorm:"not null;index" json:"subscription_id"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID" json:"-"`
+	EventID        string              `gorm:"not null" json:"event_id"`
+	EventType      string              `gorm:"not null" json:"event_type"`
+	Payload        RawJSON             `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string              `gorm:"not null;default:pending" json:"status"`
+	Attempts       int                 `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time           `gorm:"not null" json:"next_attempt_at"`
+	ResponseStatus int                 `json:"response_status,omitempty"`
+	LastError      string              `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time          `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time           `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time           `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "app_webhook_deliveries"
+}
+
+// RawJSON is an encoded JSON document stored in a JSONB column and emitted
+// as is.
+type RawJSON []byte
+
+func (r RawJSON) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+func (r *RawJSON) UnmarshalJSON(data []byte) error {
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+func (r RawJSON) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return "null", nil
+	}
+	return string(r), nil
+}
+
+func (r *RawJSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+	case []byte:
+		*r = append(RawJSON(nil), v...)
+	case string:
+		*r = RawJSON(v)
+	default:
+		return fmt.Errorf("cannot scan %T into RawJSON", value)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	db.AutoMigrate(&models.OutboxMessage{})
+	return &OutboxRepository{db: db}
+}
+
+// addToOutbox inserts messages as part of the caller's transaction.
+func addToOutbox(tx *gorm.DB, messages []models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return tx.Create(&messages).Error
+}
+
+// ClaimPending returns up to limit unsent messages whose next attempt is
+// due, oldest first. Claimed messages are pushed back by lease so that other
+// relays skip them while they are being published.
+func (r *OutboxRepository) ClaimPending(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND next_attempt_at <= ?", now).
+			Order("id").Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return messages, err
+}
+
+func (r *OutboxRepository) MarkSent(id uint) error {
+	return r.db.Model(&models.OutboxMessage{}).Where("id = ?", id).
+		Update("sent_at", time.Now()).Error
+}
+
+// MarkFailed records a failed publish and when to try again.
+func (r *OutboxRepository) MarkFailed(id uint, attempts int, lastError string, nextAttemptAt time.Time) error {
+	return r.db.Model(&models.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
+
+// DeleteSentBefore removes messages sent before t and returns how many were
+// removed. Unsent messages are kept however old they are.
+func (r *OutboxRepository) DeleteSentBefore(t time.Time) (int64, error) {
+	result := r.db.Where("sent_at IS NOT NULL AND sent_at < ?", t).Delete(&models.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
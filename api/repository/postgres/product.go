@@ -2,10 +2,11 @@ package postgres
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
-	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository struct {
@@ -23,13 +24,34 @@ func (r *ProductRepository) GetDB() *gorm.DB {
 }
 
 func (r *ProductRepository) Create(product *models.Product) error {
-	// First verify user exists
-	var user models.AppUser
-	if err := r.db.First(&user, product.UserID).Error; err != nil {
-		return fmt.Errorf("user not found: %w", err)
-	}
+	return r.CreateWithOutbox(product, nil)
+}
 
-	if err := r.db.Create(product).Error; err != nil {
+// CreateWithOutbox creates the product and adds the messages built by
+// outbox to the outbox in one transaction, so the messages are sent if and
+// only if the product is saved. outbox is called once the product has its
+// ID; it may be nil.
+func (r *ProductRepository) CreateWithOutbox(product *models.Product, outbox func(*models.Product) ([]models.OutboxMessage, error)) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// First verify user exists
+		var user models.AppUser
+		if err := tx.First(&user, product.UserID).Error; err != nil {
+			return fmt.Errorf("user not found: %w", err)
+		}
+
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		if outbox == nil {
+			return nil
+		}
+		messages, err := outbox(product)
+		if err != nil {
+			return err
+		}
+		return addToOutbox(tx, messages)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -43,32 +65,92 @@ func (r *ProductRepository) GetByID(id uint) (*models.Product, error) {
 	return &product, err
 }
 
-func (r *ProductRepository) GetByUserID(userID uint) ([]models.Product, error) {
+// GetByIDs returns the products with the given IDs in a single query.
+// Products that don't exist are left out, in no particular order.
+func (r *ProductRepository) GetByIDs(ids []uint) ([]models.Product, error) {
 	var products []models.Product
-	err := r.db.Table("app_products").Where("user_id = ?", userID).Find(&products).Error
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := r.db.Table("app_products").Preload("User").Where("id IN ?", ids).Find(&products).Error
 	return products, err
 }
 
-func (r *ProductRepository) GetFilteredProducts(userID uint, minPrice, maxPrice float64, productName string) ([]models.Product, error) {
+// productSortColumns maps the public sort fields to their columns.
+var productSortColumns = map[string]string{
+	models.ProductSortPrice:     "product_price",
+	models.ProductSortCreatedAt: "created_at",
+	models.ProductSortName:      "product_name",
+}
+
+// GetFilteredProducts returns one page of products matching filter together
+// with the total number of matching products.
+func (r *ProductRepository) GetFilteredProducts(filter models.ProductFilter) ([]models.Product, int64, error) {
 	var products []models.Product
-	query := r.db.Table("app_products").Where("user_id = ?", userID)
+	query := r.db.Table("app_products").Where("user_id = ?", filter.UserID)
 
-	if minPrice > 0 {
-		query = query.Where("product_price >= ?", minPrice)
+	if filter.MinPrice > 0 {
+		query = query.Where("product_price >= ?", filter.MinPrice)
 	}
-	if maxPrice > 0 {
-		query = query.Where("product_price <= ?", maxPrice)
+	if filter.MaxPrice > 0 {
+		query = query.Where("product_price <= ?", filter.MaxPrice)
 	}
-	if productName != "" {
-		query = query.Where("LOWER(product_name) LIKE ?", "%"+productName+"%")
+	if filter.ProductName != "" {
+		query = query.Where("LOWER(product_name) LIKE ?", "%"+filter.ProductName+"%")
 	}
+	// Let the count and the page query share the filters without sharing state
+	query = query.Session(&gorm.Session{})
 
-	err := query.Find(&products).Error
-	return products, err
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	field, desc := strings.CutPrefix(filter.Sort, "-")
+	column, ok := productSortColumns[field]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported sort field: %s", field)
+	}
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	page := query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).Limit(filter.Limit)
+	if filter.After != nil {
+		page = page.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), cursorValue(filter.After, field), filter.After.ID)
+	} else if filter.Offset > 0 {
+		page = page.Offset(filter.Offset)
+	}
+
+	err := page.Find(&products).Error
+	return products, total, err
+}
+
+func cursorValue(cursor *models.ProductCursor, field string) interface{} {
+	switch field {
+	case models.ProductSortPrice:
+		return cursor.Price
+	case models.ProductSortName:
+		return cursor.Name
+	default:
+		return cursor.CreatedAt
+	}
 }
 
 func (r *ProductRepository) Update(product *models.Product) error {
-	return r.db.Table("app_products").Save(product).Error
+	return r.db.Table("app_products").Omit("User").Save(product).Error
+}
+
+// UpdateWithOutbox saves the product and adds messages to the outbox in one
+// transaction.
+func (r *ProductRepository) UpdateWithOutbox(product *models.Product, messages []models.OutboxMessage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("app_products").Omit("User").Save(product).Error; err != nil {
+			return err
+		}
+		return addToOutbox(tx, messages)
+	})
 }
 
 func (r *ProductRepository) Delete(id uint) error {
@@ -80,7 +162,35 @@ func (r *ProductRepository) UpdateProcessingStatus(id uint, status string) error
 		Update("processing_status", status).Error
 }
 
-func (r *ProductRepository) UpdateCompressedImages(id uint, images pq.StringArray) error {
-	return r.db.Table("app_products").Model(&models.Product{}).Where("id = ?", id).
-		Update("compressed_product_images", images).Error
+// MergeImageResults applies per-image results to a product and recomputes
+// its processing status. The row is locked for the duration so concurrent
+// tasks for the same product cannot overwrite each other's results.
+func (r *ProductRepository) MergeImageResults(id uint, results []models.ImageResult) (*models.Product, error) {
+	return r.MergeImageResultsWithOutbox(id, results, nil)
+}
+
+// MergeImageResultsWithOutbox merges results like MergeImageResults and adds
+// messages to the outbox in the same transaction.
+func (r *ProductRepository) MergeImageResultsWithOutbox(id uint, results []models.ImageResult, messages []models.OutboxMessage) (*models.Product, error) {
+	var product models.Product
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+			return err
+		}
+
+		product.ImageVariants = models.MergeImageResults(product.ProductImages, product.ImageVariants, results)
+		product.ProcessingStatus = models.ProcessingStatusOf(product.ImageVariants)
+		err := tx.Model(&product).Updates(map[string]interface{}{
+			"image_variants":    product.ImageVariants,
+			"processing_status": product.ProcessingStatus,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return addToOutbox(tx, messages)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
 }
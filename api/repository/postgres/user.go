@@ -23,6 +23,12 @@ func (r *UserRepository) GetByID(id uint) (*models.AppUser, error) {
 	return &user, err
 }
 
+func (r *UserRepository) List() ([]models.AppUser, error) {
+	var users []models.AppUser
+	err := r.db.Order("id").Find(&users).Error
+	return users, err
+}
+
 func (r *UserRepository) Update(user *models.AppUser) error {
 	return r.db.Save(user).Error
 }
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{})
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Create(subscription).Error
+}
+
+func (r *WebhookRepository) GetSubscription(id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := r.db.First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(userID uint) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ListSubscribers returns the user's active subscriptions to eventType.
+func (r *WebhookRepository) ListSubscribers(userID uint, eventType string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Where("user_id = ? AND active AND ? = ANY(events)", userID, eventType).
+		Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// DeleteSubscription removes a subscription together with its deliveries.
+func (r *WebhookRepository) DeleteSubscription(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookSubscription{}, id).Error
+	})
+}
+
+func (r *WebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Omit("Subscription").Create(&deliveries).Error
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next
+// attempt is due, with their subscriptions. Claimed deliveries are pushed
+// back by lease so that other workers skip them while they are being sent.
+func (r *WebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at").Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", deliveryIDs(deliveries)).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+
+	var claimed []models.WebhookDelivery
+	err = r.db.Preload("Subscription").Order("id").Find(&claimed, deliveryIDs(deliveries)).Error
+	return claimed, err
+}
+
+func deliveryIDs(deliveries []models.WebhookDelivery) []uint {
+	ids := make([]uint, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+	}
+	return ids
+}
+
+// UpdateDelivery records the outcome of an attempt.
+func (r *WebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Model(delivery).Select(
+		"status", "attempts", "next_attempt_at", "response_status", "last_error", "delivered_at",
+	).Updates(delivery).Error
+}
+
+// ListDeliveries returns the subscription's most recent deliveries first.
+func (r *WebhookRepository) ListDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
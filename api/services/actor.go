@@ -0,0 +1,14 @@
+package services
+
+// Actor is the authenticated principal a service call is made on behalf of.
+// It is derived from the access token, never from request bodies.
+type Actor struct {
+	UserID uint
+	Admin  bool
+}
+
+// CanAccess reports whether the actor may read or modify data owned by ownerID.
+// Admins may act on any user's data.
+func (a Actor) CanAccess(ownerID uint) bool {
+	return a.Admin || a.UserID == ownerID
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+)
+
+// DeadLetterQueue is the dead letter queue of image processing tasks.
+type DeadLetterQueue interface {
+	List(ctx context.Context, filter messaging.DeadLetterFilter, limit int) ([]messaging.DeadLetter, error)
+	Purge(ctx context.Context, filter messaging.DeadLetterFilter) (int, error)
+	Replay(ctx context.Context, filter messaging.DeadLetterFilter) ([]messaging.DeadLetter, error)
+}
+
+// ProductCacheInvalidator drops the cached copies of a product. It is
+// implemented by ProductService.
+type ProductCacheInvalidator interface {
+	InvalidateCache(product *models.Product) error
+}
+
+// DeadLetterFilter selects dead-lettered tasks. Purge and Replay refuse an
+// empty filter unless All is set.
+type DeadLetterFilter struct {
+	IDs        []string `json:"ids"`
+	ProductID  uint     `json:"product_id"`
+	ErrorClass string   `json:"error_class"`
+	All        bool     `json:"all"`
+}
+
+func (f DeadLetterFilter) queueFilter() messaging.DeadLetterFilter {
+	return messaging.DeadLetterFilter{
+		IDs:        f.IDs,
+		ProductID:  f.ProductID,
+		ErrorClass: f.ErrorClass,
+	}
+}
+
+// DeadLetterService lets admins act on image tasks that ran out of retries.
+type DeadLetterService struct {
+	queue       DeadLetterQueue
+	productRepo ProductRepository
+	cache       ProductCacheInvalidator
+}
+
+func NewDeadLetterService(queue DeadLetterQueue, repo ProductRepository, cache ProductCacheInvalidator) *DeadLetterService {
+	return &DeadLetterService{
+		queue:       queue,
+		productRepo: repo,
+		cache:       cache,
+	}
+}
+
+func (s *DeadLetterService) ListDeadLetters(filter DeadLetterFilter, limit int) ([]messaging.DeadLetter, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidFilter)
+	}
+	return s.queue.List(context.Background(), filter.queueFilter(), limit)
+}
+
+func (s *DeadLetterService) GetDeadLetter(id string) (*messaging.DeadLetter, error) {
+	matches, err := s.queue.List(context.Background(), messaging.DeadLetterFilter{IDs: []string{id}}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return &matches[0], nil
+}
+
+func (s *DeadLetterService) PurgeDeadLetters(filter DeadLetterFilter) (int, error) {
+	if err := checkBulkFilter(filter); err != nil {
+		return 0, err
+	}
+	return s.queue.Purge(context.Background(), filter.queueFilter())
+}
+
+// ReplayDeadLetters sends the matching tasks back to the processing queue and
+// marks their images pending again, dropping the cached copies of the
+// products it resets.
+func (s *DeadLetterService) ReplayDeadLetters(filter DeadLetterFilter) ([]messaging.DeadLetter, error) {
+	if err := checkBulkFilter(filter); err != nil {
+		return nil, err
+	}
+
+	replayed, err := s.queue.Replay(context.Background(), filter.queueFilter())
+
+	// Reset whatever was replayed, even if the queue failed part way
+	var products []uint
+	seen := make(map[uint]bool)
+	results := make(map[uint][]models.ImageResult)
+	for _, m := range replayed {
+		if !seen[m.ProductID] {
+			seen[m.ProductID] = true
+			products = append(products, m.ProductID)
+		}
+		for _, source := range m.Images {
+			results[m.ProductID] = append(results[m.ProductID], models.ImageResult{
+				Source: source,
+				Status: models.ImageStatusPending,
+			})
+		}
+	}
+	for _, id := range products {
+		product, err := s.productRepo.MergeImageResults(id, results[id])
+		if err != nil {
+			log.Printf("Failed to reset status of product %d: %v", id, err)
+			continue
+		}
+		if err := s.cache.InvalidateCache(product); err != nil {
+			log.Printf("Failed to invalidate cache of product %d: %v", id, err)
+		}
+	}
+
+	return replayed, err
+}
+
+func checkBulkFilter(filter DeadLetterFilter) error {
+	if filter.queueFilter().Empty() && !filter.All {
+		return fmt.Errorf("%w: select messages or set all", ErrInvalidFilter)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrInvalidProduct  = errors.New("invalid product")
+	ErrInvalidFilter   = errors.New("invalid filter")
+	ErrInvalidBatch    = errors.New("invalid batch")
+	ErrInvalidImage    = errors.New("invalid image")
+	ErrNoFailedImages  = errors.New("no failed images to reprocess")
+	ErrForbidden       = errors.New("forbidden")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidRole     = errors.New("invalid role")
+
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenReused is returned when an already rotated refresh token is
+	// presented again; the token family has been revoked.
+	ErrTokenReused = fmt.Errorf("%w: refresh token reused", ErrInvalidToken)
+)
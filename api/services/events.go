@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
+)
+
+// EventStream keeps ordered events that readers can wait for and resume
+// after. It is satisfied by cache.EventStream.
+type EventStream interface {
+	Publish(ctx context.Context, stream string, data []byte) (string, error)
+	Last(ctx context.Context, stream string) (string, error)
+	Read(ctx context.Context, stream, after string, wait time.Duration) ([]cache.StreamEntry, error)
+}
+
+// ProcessingEvent reports the processing status of a product and its
+// images. ID orders events of the same product.
+type ProcessingEvent struct {
+	ID               string               `json:"-"`
+	ProductID        uint                 `json:"product_id"`
+	ProcessingStatus string               `json:"processing_status"`
+	Images           models.ImageVariants `json:"images"`
+	At               time.Time            `json:"at"`
+}
+
+type ProcessingEventService struct {
+	stream EventStream
+}
+
+func NewProcessingEventService(stream EventStream) *ProcessingEventService {
+	return &ProcessingEventService{stream: stream}
+}
+
+func processingStream(productID uint) string {
+	return fmt.Sprintf("product:%d:processing", productID)
+}
+
+// PublishProcessingStatus records the current processing status of product.
+func (s *ProcessingEventService) PublishProcessingStatus(product *models.Product) error {
+	data, err := json.Marshal(ProcessingEvent{
+		ProductID:        product.ID,
+		ProcessingStatus: product.ProcessingStatus,
+		Images:           product.ImageVariants,
+		At:               time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.stream.Publish(context.Background(), processingStream(product.ID), data)
+	return err
+}
+
+// LastProcessingEvent returns the ID of the product's latest event, which
+// reading after picks up every later event.
+func (s *ProcessingEventService) LastProcessingEvent(ctx context.Context, productID uint) (string, error) {
+	return s.stream.Last(ctx, processingStream(productID))
+}
+
+// ReadProcessingEvents returns the product's events after the given ID,
+// waiting up to wait for one to arrive.
+func (s *ProcessingEventService) ReadProcessingEvents(ctx context.Context, productID uint, after string, wait time.Duration) ([]ProcessingEvent, error) {
+	entries, err := s.stream.Read(ctx, processingStream(productID), after, wait)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ProcessingEvent, 0, len(entries))
+	for _, entry := range entries {
+		var event ProcessingEvent
+		if err := json.Unmarshal(entry.Data, &event); err != nil {
+			return nil, fmt.Errorf("invalid event %s: %w", entry.ID, err)
+		}
+		event.ID = entry.ID
+		events = append(events, event)
+	}
+	return events, nil
+}
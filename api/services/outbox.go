@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+)
+
+type OutboxRepository interface {
+	ClaimPending(limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	MarkSent(id uint) error
+	MarkFailed(id uint, attempts int, lastError string, nextAttemptAt time.Time) error
+	DeleteSentBefore(t time.Time) (int64, error)
+}
+
+type OutboxConfig struct {
+	// BatchSize is the number of messages claimed at once.
+	BatchSize int
+	// PollInterval is how often the relay looks for unsent messages.
+	PollInterval time.Duration
+	// Lease is how long a claimed message is hidden from other relays.
+	Lease time.Duration
+	// Backoff is the wait after the first failed publish, doubling after
+	// each further failure up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retention is how long sent messages are kept before cleanup.
+	Retention time.Duration
+	// CleanupInterval is how often sent messages past Retention are removed.
+	CleanupInterval time.Duration
+}
+
+const (
+	defaultOutboxBatchSize       = 100
+	defaultOutboxPollInterval    = time.Second
+	defaultOutboxLease           = 30 * time.Second
+	defaultOutboxBackoff         = time.Second
+	defaultOutboxMaxBackoff      = 5 * time.Minute
+	defaultOutboxRetention       = 7 * 24 * time.Hour
+	defaultOutboxCleanupInterval = time.Hour
+
+	maxOutboxErrorLength = 500
+)
+
+// OutboxRelay publishes messages written to the outbox. A message is marked
+// sent only after the broker accepted it, so delivery is at least once:
+// a relay that stops between the two publishes the message again later.
+type OutboxRelay struct {
+	repo      OutboxRepository
+	publisher messaging.Publisher
+	cfg       OutboxConfig
+}
+
+func NewOutboxRelay(repo OutboxRepository, publisher messaging.Publisher, cfg OutboxConfig) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultOutboxBatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultOutboxPollInterval
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaultOutboxLease
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultOutboxBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultOutboxMaxBackoff
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultOutboxRetention
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = defaultOutboxCleanupInterval
+	}
+
+	return &OutboxRelay{repo: repo, publisher: publisher, cfg: cfg}
+}
+
+// Run relays messages and removes old sent ones until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(r.cfg.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		// Keep going while there is a backlog
+		for {
+			n, err := r.RelayPending(ctx)
+			if err != nil {
+				log.Printf("Failed to relay outbox: %v", err)
+			}
+			if err != nil || n < r.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cleanup.C:
+			if _, err := r.Cleanup(); err != nil {
+				log.Printf("Failed to clean up outbox: %v", err)
+			}
+		}
+	}
+}
+
+// RelayPending publishes one batch of due messages and returns how many it
+// claimed. Messages that fail to publish are retried after the backoff.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	messages, err := r.repo.ClaimPending(r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, message := range messages {
+		// Messages left unpublished are picked up again once their claim
+		// expires
+		if ctx.Err() != nil {
+			break
+		}
+		r.relay(message)
+	}
+	return len(messages), nil
+}
+
+func (r *OutboxRelay) relay(message models.OutboxMessage) {
+	if err := r.publisher.Publish(message.Queue, message.Payload); err != nil {
+		attempts := message.Attempts + 1
+		lastError := err.Error()
+		if len(lastError) > maxOutboxErrorLength {
+			lastError = lastError[:maxOutboxErrorLength]
+		}
+		log.Printf("Failed to publish outbox message %d to %s (attempt %d): %v", message.ID, message.Queue, attempts, err)
+		if err := r.repo.MarkFailed(message.ID, attempts, lastError, time.Now().Add(r.backoff(attempts))); err != nil {
+			log.Printf("Failed to record outbox message %d: %v", message.ID, err)
+		}
+		return
+	}
+
+	if err := r.repo.MarkSent(message.ID); err != nil {
+		// The message goes out again when its claim expires
+		log.Printf("Failed to mark outbox message %d sent: %v", message.ID, err)
+	}
+}
+
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.cfg.Backoff
+	for i := 1; i < attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.cfg.MaxBackoff)
+}
+
+// Cleanup removes messages sent longer ago than the retention period and
+// returns how many it removed.
+func (r *OutboxRelay) Cleanup() (int64, error) {
+	return r.repo.DeleteSentBefore(time.Now().Add(-r.cfg.Retention))
+}
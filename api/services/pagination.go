@@ -0,0 +1,80 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	defaultSort      = models.ProductSortCreatedAt
+)
+
+// ProductPage is the response envelope of product listings. NextCursor is
+// empty on the last page.
+type ProductPage struct {
+	Items      []models.Product `json:"items"`
+	NextCursor string           `json:"next_cursor"`
+	Total      int64            `json:"total"`
+}
+
+// normalizePage applies defaults and bounds to the paging fields of req.
+func normalizePage(req *FilterProductsRequest) error {
+	if req.Sort == "" {
+		req.Sort = defaultSort
+	}
+	switch strings.TrimPrefix(req.Sort, "-") {
+	case models.ProductSortPrice, models.ProductSortCreatedAt, models.ProductSortName:
+	default:
+		return fmt.Errorf("%w: unsupported sort %q", ErrInvalidFilter, req.Sort)
+	}
+
+	if req.Limit < 0 || req.Offset < 0 {
+		return fmt.Errorf("%w: limit and offset must not be negative", ErrInvalidFilter)
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultPageLimit
+	}
+	if req.Limit > maxPageLimit {
+		req.Limit = maxPageLimit
+	}
+	return nil
+}
+
+// encodeCursor builds the opaque cursor pointing after product.
+func encodeCursor(sort string, product models.Product) string {
+	cursor := models.ProductCursor{Sort: sort, ID: product.ID}
+	switch strings.TrimPrefix(sort, "-") {
+	case models.ProductSortPrice:
+		cursor.Price = product.ProductPrice
+	case models.ProductSortName:
+		cursor.Name = product.ProductName
+	default:
+		cursor.CreatedAt = product.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor and checks it was issued for the same sort.
+func decodeCursor(value, sort string) (*models.ProductCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+
+	var cursor models.ProductCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidFilter, cursor.Sort)
+	}
+	return &cursor, nil
+}
@@ -1,50 +1,79 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
-	"github.com/go-redis/redis"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
 )
 
-type ImageProcessingTask struct {
-	ProductID uint     `json:"product_id"`
-	Images    []string `json:"images"`
-}
-
 type ProductRepository interface {
 	Create(product *models.Product) error
+	CreateWithOutbox(product *models.Product, outbox func(*models.Product) ([]models.OutboxMessage, error)) error
 	GetByID(id uint) (*models.Product, error)
-	GetByUserID(userID uint) ([]models.Product, error)
+	GetByIDs(ids []uint) ([]models.Product, error)
 	Update(product *models.Product) error
+	UpdateWithOutbox(product *models.Product, messages []models.OutboxMessage) error
+	Delete(id uint) error
 	UpdateProcessingStatus(id uint, status string) error
-	UpdateCompressedImages(id uint, images pq.StringArray) error
-	GetFilteredProducts(userID uint, minPrice, maxPrice float64, productName string) ([]models.Product, error)
+	MergeImageResults(id uint, results []models.ImageResult) (*models.Product, error)
+	MergeImageResultsWithOutbox(id uint, results []models.ImageResult, messages []models.OutboxMessage) (*models.Product, error)
+	GetFilteredProducts(filter models.ProductFilter) ([]models.Product, int64, error)
 }
 
 type Cache interface {
 	Get(ctx context.Context, key string, dest interface{}) error
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Delete(ctx context.Context, key string) error
+	// BatchGetInto decodes the entry under each key of dests into the
+	// pointer it maps to and returns the keys it couldn't.
+	BatchGetInto(ctx context.Context, dests map[string]interface{}) ([]string, error)
+	// SetWithTags caches value like Set and tags the key, so that
+	// InvalidateTags drops it along with every other key sharing a tag.
+	SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// ImageStore stores uploaded original images. It is satisfied by every
+// storage.Blob backend.
+type ImageStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	URL(key string) string
 }
 
 type ProductService struct {
 	productRepo ProductRepository
-	mqPublisher messaging.Publisher
 	cache       Cache
+	cacheCfg    ProductCacheConfig
+	// loads coalesces concurrent loads of the same cache key.
+	loads      singleflight.Group
+	imageStore ImageStore
+	events     EventEmitter
 }
 
+// FilterProductsRequest selects a page of products. Pages are addressed
+// either by Offset or by the opaque Cursor returned with the previous page.
 type FilterProductsRequest struct {
 	UserID      uint    `json:"user_id"`
 	MinPrice    float64 `json:"min_price"`
 	MaxPrice    float64 `json:"max_price"`
 	ProductName string  `json:"product_name"`
+	Sort        string  `json:"sort"`
+	Limit       int     `json:"limit"`
+	Offset      int     `json:"offset"`
+	Cursor      string  `json:"cursor"`
 }
 
 const (
@@ -74,11 +103,28 @@ func (s *ProductService) getCacheKey(prefix string, id interface{}) string {
 	return fmt.Sprintf("%s%v", prefix, id)
 }
 
-func NewProductService(repo ProductRepository, publisher messaging.Publisher, cache Cache) *ProductService {
+// userCacheTag tags the cached listings of a user's products.
+func (s *ProductService) userCacheTag(userID uint) string {
+	return s.getCacheKey(userCachePrefix, userID)
+}
+
+// NewProductService creates the product service. events may be nil when
+// nothing subscribes to product events. Image processing tasks are written
+// to the outbox alongside the product and published by an OutboxRelay.
+func NewProductService(repo ProductRepository, cache Cache, imageStore ImageStore, events EventEmitter, cacheCfg ProductCacheConfig) *ProductService {
+	if cacheCfg.Jitter == 0 {
+		cacheCfg.Jitter = defaultCacheJitter
+	}
+	if cacheCfg.NotFoundTTL <= 0 {
+		cacheCfg.NotFoundTTL = defaultNotFoundCacheTTL
+	}
+
 	return &ProductService{
 		productRepo: repo,
-		mqPublisher: publisher,
 		cache:       cache,
+		cacheCfg:    cacheCfg,
+		imageStore:  imageStore,
+		events:      events,
 	}
 }
 
@@ -90,110 +136,595 @@ type CreateProductRequest struct {
 	Images      []string `json:"product_images"`
 }
 
-func (s *ProductService) CreateProduct(req *CreateProductRequest) (*models.Product, error) {
+// UpdateProductRequest holds the editable fields of a product. PUT replaces
+// all of them, PATCH merges a JSON merge patch (RFC 7396) on top of the
+// current values before validation.
+type UpdateProductRequest struct {
+	Name        string   `json:"product_name"`
+	Description string   `json:"product_description"`
+	Price       float64  `json:"product_price"`
+	Images      []string `json:"product_images"`
+}
+
+func (r *UpdateProductRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: product_name is required", ErrInvalidProduct)
+	}
+	if r.Price <= 0 {
+		return fmt.Errorf("%w: product_price must be greater than zero", ErrInvalidProduct)
+	}
+	for _, image := range r.Images {
+		if image == "" {
+			return fmt.Errorf("%w: product_images must not contain empty urls", ErrInvalidProduct)
+		}
+	}
+	return nil
+}
+
+// CreateProduct creates a product owned by the actor. Only admins may create
+// products on behalf of another user by setting UserID.
+func (s *ProductService) CreateProduct(actor Actor, req *CreateProductRequest) (*models.Product, error) {
+	if req.UserID == 0 {
+		req.UserID = actor.UserID
+	}
+	if !actor.CanAccess(req.UserID) {
+		return nil, ErrForbidden
+	}
+
 	product := &models.Product{
 		UserID:             req.UserID,
 		ProductName:        req.Name,
 		ProductDescription: req.Description,
 		ProductPrice:       req.Price,
 		ProductImages:      pq.StringArray(req.Images), // Ensure correct array type
-		ProcessingStatus:   "pending",
+		ImageVariants:      models.PendingImages(req.Images),
+		ProcessingStatus:   models.ProcessingStatusPending,
 	}
 
-	if err := s.productRepo.Create(product); err != nil {
+	// Queue the image processing task with the product
+	outbox := func(product *models.Product) ([]models.OutboxMessage, error) {
+		return imageProcessingMessages(product.ID, req.Images)
+	}
+	if err := s.productRepo.CreateWithOutbox(product, outbox); err != nil {
 		return nil, err
 	}
+	s.invalidateProductCaches(product)
+	s.emit(models.EventProductCreated, product)
 
-	// Queue image processing task
-	task := ImageProcessingTask{
-		ProductID: product.ID,
-		Images:    req.Images,
+	return product, nil
+}
+
+func (s *ProductService) handleCacheError(err error, operation string) {
+	if !errors.Is(err, cache.ErrMiss) {
+		log.Printf("Cache %s error: %v", operation, err)
 	}
+}
 
-	if err := s.queueImageProcessing(task); err != nil {
-		return product, err
+func (s *ProductService) GetProduct(actor Actor, id uint) (*models.Product, error) {
+	cacheKey := s.getCacheKey(productCachePrefix, id)
+	product, err := cachedLoad(s, cacheKey, s.getCacheDuration("product"), nil, func() (*models.Product, error) {
+		return s.loadProduct(id)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	if !actor.CanAccess(product.UserID) {
+		return nil, ErrForbidden
+	}
 	return product, nil
 }
 
-func (s *ProductService) handleCacheError(err error, operation string) {
-	if err != redis.Nil {
-		log.Printf("Cache %s error: %v", operation, err)
-	}
+// maxBatchProducts bounds the IDs looked up by one GetProducts call.
+const maxBatchProducts = 100
+
+// Outcomes of looking up a product with GetProducts.
+const (
+	LookupOK        = "ok"
+	LookupNotFound  = "not_found"
+	LookupForbidden = "forbidden"
+)
+
+// ProductLookup is the outcome of looking up one product ID. Product is only
+// set when Status is LookupOK.
+type ProductLookup struct {
+	ID      uint            `json:"id"`
+	Status  string          `json:"status"`
+	Product *models.Product `json:"product,omitempty"`
 }
 
-func (s *ProductService) GetProduct(id uint) (*models.Product, error) {
+// GetProducts looks up many products at once and reports on each ID, in the
+// order given with duplicates dropped. Cached products are read in one round
+// trip and the others in a single query, after which they are cached too.
+func (s *ProductService) GetProducts(actor Actor, ids []uint) ([]ProductLookup, error) {
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 || len(ids) > maxBatchProducts {
+		return nil, fmt.Errorf("%w: between 1 and %d product ids are required", ErrInvalidBatch, maxBatchProducts)
+	}
+
 	ctx := context.Background()
-	cacheKey := s.getCacheKey(productCachePrefix, id)
+	ttl := s.getCacheDuration("product")
+	keyIDs := make(map[string]uint, len(ids))
+	entries := make(map[uint]*CacheEntry[*models.Product], len(ids))
+	dests := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		key := s.getCacheKey(productCachePrefix, id)
+		entry := &CacheEntry[*models.Product]{}
+		keyIDs[key] = id
+		entries[id] = entry
+		dests[key] = entry
+	}
+
+	missing, err := s.cache.BatchGetInto(ctx, dests)
+	if err != nil {
+		s.handleCacheError(err, "get")
+		missing = nil
+		for key := range dests {
+			missing = append(missing, key)
+		}
+	}
+	for _, key := range missing {
+		delete(entries, keyIDs[key])
+	}
+
+	now := time.Now()
+	var load []uint
+	for _, id := range ids {
+		entry, ok := entries[id]
+		switch {
+		case !ok || (!entry.NotFound && entry.Value == nil):
+			load = append(load, id)
+		case now.Before(entry.StaleAt):
+		case now.Before(entry.StaleAt.Add(s.cacheCfg.StaleWhileRevalidate)):
+			refreshCache(s, s.getCacheKey(productCachePrefix, id), ttl, nil, func() (*models.Product, error) {
+				return s.loadProduct(id)
+			})
+		default:
+			load = append(load, id)
+		}
+	}
+
+	if len(load) > 0 {
+		products, err := s.productRepo.GetByIDs(load)
+		if err != nil {
+			return nil, err
+		}
+
+		loaded := make(map[uint]*models.Product, len(products))
+		for i := range products {
+			loaded[products[i].ID] = &products[i]
+		}
+		for _, id := range load {
+			entry := &CacheEntry[*models.Product]{Value: loaded[id], NotFound: loaded[id] == nil}
+			entries[id] = entry
+			storeCacheEntry(s, s.getCacheKey(productCachePrefix, id), *entry, ttl, nil)
+		}
+	}
+
+	lookups := make([]ProductLookup, len(ids))
+	for i, id := range ids {
+		entry := entries[id]
+		switch {
+		case entry.NotFound:
+			lookups[i] = ProductLookup{ID: id, Status: LookupNotFound}
+		case !actor.CanAccess(entry.Value.UserID):
+			lookups[i] = ProductLookup{ID: id, Status: LookupForbidden}
+		default:
+			lookups[i] = ProductLookup{ID: id, Status: LookupOK, Product: entry.Value}
+		}
+	}
+	return lookups, nil
+}
+
+// uniqueIDs drops repeated IDs, keeping the first of each.
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
 
-	var product *models.Product
-	err := s.cache.Get(ctx, cacheKey, &product)
-	if err == nil {
-		return product, nil
+// GetFilteredProducts lists the products of req.UserID, defaulting to the
+// actor's own products when no user is given.
+func (s *ProductService) GetFilteredProducts(actor Actor, req *FilterProductsRequest) (*ProductPage, error) {
+	if req.UserID == 0 {
+		req.UserID = actor.UserID
+	}
+	if !actor.CanAccess(req.UserID) {
+		return nil, ErrForbidden
+	}
+	if err := normalizePage(req); err != nil {
+		return nil, err
+	}
+
+	filter := models.ProductFilter{
+		UserID:      req.UserID,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		ProductName: req.ProductName,
+		Sort:        req.Sort,
+		// Fetch one extra row to learn whether there is a next page
+		Limit:  req.Limit + 1,
+		Offset: req.Offset,
+	}
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor, req.Sort)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = cursor
 	}
-	s.handleCacheError(err, "get")
 
-	// Use existing err variable
-	product, err = s.productRepo.GetByID(id)
+	cacheKey := fmt.Sprintf("%s%d:minPrice:%f:maxPrice:%f:productName:%s:sort:%s:limit:%d:offset:%d:cursor:%s",
+		listCachePrefix, req.UserID, req.MinPrice, req.MaxPrice, req.ProductName,
+		req.Sort, req.Limit, req.Offset, req.Cursor)
+
+	tags := []string{s.userCacheTag(req.UserID)}
+	return cachedLoad(s, cacheKey, s.getCacheDuration("list"), tags, func() (*ProductPage, error) {
+		products, total, err := s.productRepo.GetFilteredProducts(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		page := &ProductPage{Items: products, Total: total}
+		if len(products) > req.Limit {
+			page.Items = products[:req.Limit]
+			page.NextCursor = encodeCursor(req.Sort, page.Items[req.Limit-1])
+		}
+		if page.Items == nil {
+			page.Items = []models.Product{}
+		}
+		return page, nil
+	})
+}
+
+// imageProcessingMessages returns the outbox message queueing processing of
+// the product's images.
+func imageProcessingMessages(productID uint, images []string) ([]models.OutboxMessage, error) {
+	taskBytes, err := messaging.EncodeImageProcessingTask(messaging.ImageProcessingTask{
+		ProductID: productID,
+		Images:    images,
+	}, "")
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.cache.Set(ctx, cacheKey, product, s.getCacheDuration("product")); err != nil {
-		s.handleCacheError(err, "set")
+	return []models.OutboxMessage{{Queue: messaging.ImageProcessingQueue, Payload: taskBytes}}, nil
+}
+
+// ProductEvent is the data of product webhook events.
+type ProductEvent struct {
+	ID               uint                 `json:"id"`
+	UserID           uint                 `json:"user_id"`
+	Name             string               `json:"product_name"`
+	Description      string               `json:"product_description"`
+	Price            float64              `json:"product_price"`
+	Images           []string             `json:"product_images"`
+	ImageVariants    models.ImageVariants `json:"image_variants"`
+	ProcessingStatus string               `json:"processing_status"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// emit notifies subscribers of a product event. Failing to do so doesn't
+// fail the change that caused it.
+func (s *ProductService) emit(eventType string, product *models.Product) {
+	if s.events == nil {
+		return
 	}
 
-	return product, nil
+	err := s.events.Emit(product.UserID, eventType, ProductEvent{
+		ID:               product.ID,
+		UserID:           product.UserID,
+		Name:             product.ProductName,
+		Description:      product.ProductDescription,
+		Price:            product.ProductPrice,
+		Images:           product.ProductImages,
+		ImageVariants:    product.ImageVariants,
+		ProcessingStatus: product.ProcessingStatus,
+		CreatedAt:        product.CreatedAt,
+		UpdatedAt:        product.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to emit %s for product %d: %v", eventType, product.ID, err)
+	}
 }
 
-func (s *ProductService) GetUserProducts(userID uint) ([]models.Product, error) {
-	return s.productRepo.GetByUserID(userID)
+// ProductProcessed notifies subscribers that image processing of product has
+// finished.
+func (s *ProductService) ProductProcessed(product *models.Product) {
+	s.emit(models.EventProductProcessed, product)
 }
 
-func (s *ProductService) GetFilteredProducts(req *FilterProductsRequest) ([]models.Product, error) {
+// InvalidateCache drops the cached product and every cached listing of its
+// owner, since any of them may contain the stale row.
+func (s *ProductService) InvalidateCache(product *models.Product) error {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("%s%d:minPrice:%f:maxPrice:%f:productName:%s",
-		listCachePrefix, req.UserID, req.MinPrice, req.MaxPrice, req.ProductName)
+	if err := s.cache.Delete(ctx, s.getCacheKey(productCachePrefix, product.ID)); err != nil {
+		return err
+	}
+	return s.cache.InvalidateTags(ctx, s.userCacheTag(product.UserID))
+}
+
+// invalidateProductCaches is InvalidateCache for mutations, which succeed
+// even when the cache can't be cleared.
+func (s *ProductService) invalidateProductCaches(product *models.Product) {
+	if err := s.InvalidateCache(product); err != nil {
+		s.handleCacheError(err, "delete")
+	}
+}
+
+func (s *ProductService) UpdateProduct(product *models.Product) error {
+	err := s.productRepo.Update(product)
+	if err != nil {
+		return err
+	}
+	return s.InvalidateCache(product)
+}
+
+func (s *ProductService) loadProduct(id uint) (*models.Product, error) {
+	product, err := s.productRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
 
-	var products []models.Product
-	err := s.cache.Get(ctx, cacheKey, &products)
-	if err == nil {
-		return products, nil
+// loadOwnedProduct loads a product and checks the actor may modify it.
+func (s *ProductService) loadOwnedProduct(actor Actor, id uint) (*models.Product, error) {
+	product, err := s.loadProduct(id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanAccess(product.UserID) {
+		return nil, ErrForbidden
+	}
+	return product, nil
+}
+
+// ReplaceProduct overwrites all editable fields of a product (PUT semantics).
+func (s *ProductService) ReplaceProduct(actor Actor, id uint, req *UpdateProductRequest) (*models.Product, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	product, err := s.loadOwnedProduct(actor, id)
+	if err != nil {
+		return nil, err
 	}
-	s.handleCacheError(err, "get")
 
-	products, err = s.productRepo.GetFilteredProducts(req.UserID, req.MinPrice, req.MaxPrice, req.ProductName)
+	return s.applyUpdate(product, req)
+}
+
+// PatchProduct applies a JSON merge patch to the editable fields of a product.
+// Keys set to null are reset to their zero value.
+func (s *ProductService) PatchProduct(actor Actor, id uint, patch []byte) (*models.Product, error) {
+	product, err := s.loadOwnedProduct(actor, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.cache.Set(ctx, cacheKey, products, s.getCacheDuration("list")); err != nil {
-		s.handleCacheError(err, "set")
+	req, err := mergeProductPatch(product, patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
-	return products, nil
+	return s.applyUpdate(product, req)
 }
 
-func (s *ProductService) queueImageProcessing(task ImageProcessingTask) error {
-	taskBytes, err := json.Marshal(task)
+func (s *ProductService) DeleteProduct(actor Actor, id uint) error {
+	product, err := s.loadOwnedProduct(actor, id)
 	if err != nil {
 		return err
 	}
 
-	return s.mqPublisher.Publish("image_processing", taskBytes)
+	if err := s.productRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.invalidateProductCaches(product)
+	s.emit(models.EventProductDeleted, product)
+	return nil
+}
+
+// applyUpdate persists req onto product. When the source images change the
+// previously generated variants are discarded and processing is queued again.
+func (s *ProductService) applyUpdate(product *models.Product, req *UpdateProductRequest) (*models.Product, error) {
+	imagesChanged := !equalImages(product.ProductImages, req.Images)
+
+	product.ProductName = req.Name
+	product.ProductDescription = req.Description
+	product.ProductPrice = req.Price
+	if imagesChanged {
+		product.ProductImages = pq.StringArray(req.Images)
+		product.ImageVariants = models.PendingImages(req.Images)
+		product.ProcessingStatus = models.ProcessingStatusPending
+	}
+
+	var messages []models.OutboxMessage
+	if imagesChanged {
+		var err error
+		if messages, err = imageProcessingMessages(product.ID, req.Images); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.productRepo.UpdateWithOutbox(product, messages); err != nil {
+		return nil, err
+	}
+	s.invalidateProductCaches(product)
+	s.emit(models.EventProductUpdated, product)
+
+	return product, nil
+}
+
+func mergeProductPatch(product *models.Product, patch []byte) (*UpdateProductRequest, error) {
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidProduct)
+	}
+
+	current, err := json.Marshal(UpdateProductRequest{
+		Name:        product.ProductName,
+		Description: product.ProductDescription,
+		Price:       product.ProductPrice,
+		Images:      product.ProductImages,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var currentDoc interface{}
+	if err := json.Unmarshal(current, &currentDoc); err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(applyMergePatch(currentDoc, patchDoc))
+	if err != nil {
+		return nil, err
+	}
+
+	var req UpdateProductRequest
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	return &req, nil
+}
+
+// applyMergePatch implements the MergePatch algorithm from RFC 7396.
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = applyMergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+func equalImages(current pq.StringArray, next []string) bool {
+	if len(current) != len(next) {
+		return false
+	}
+	for i := range current {
+		if current[i] != next[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// UploadedImage is an image file received through a multipart upload.
+type UploadedImage struct {
+	Filename string
+	Data     []byte
 }
 
-func (s *ProductService) InvalidateCache(id uint) error {
+// uploadContentTypes are the sniffed content types the image processor can
+// decode, mapped to the extension used for the stored original.
+var uploadContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// AddProductImages stores uploaded originals, appends them to the product's
+// images and queues processing for just the new images.
+func (s *ProductService) AddProductImages(actor Actor, id uint, images []UploadedImage) (*models.Product, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%w: no images uploaded", ErrInvalidImage)
+	}
+
+	contentTypes := make([]string, len(images))
+	for i, image := range images {
+		contentType := http.DetectContentType(image.Data)
+		if _, ok := uploadContentTypes[contentType]; !ok {
+			return nil, fmt.Errorf("%w: %s has unsupported content type %s", ErrInvalidImage, image.Filename, contentType)
+		}
+		contentTypes[i] = contentType
+	}
+
+	product, err := s.loadOwnedProduct(actor, id)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("product:%d", id)
-	return s.cache.Delete(ctx, cacheKey)
+	urls := make([]string, 0, len(images))
+	for i, image := range images {
+		key := fmt.Sprintf("products/%d/originals/%s%s", product.ID, uuid.New().String(), uploadContentTypes[contentTypes[i]])
+		if err := s.imageStore.Put(ctx, key, image.Data, contentTypes[i]); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", image.Filename, err)
+		}
+		urls = append(urls, s.imageStore.URL(key))
+	}
+
+	product.ProductImages = append(product.ProductImages, urls...)
+	product.ImageVariants = append(product.ImageVariants, models.PendingImages(urls)...)
+	product.ProcessingStatus = models.ProcessingStatusOf(product.ImageVariants)
+	messages, err := imageProcessingMessages(product.ID, urls)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.productRepo.UpdateWithOutbox(product, messages); err != nil {
+		return nil, err
+	}
+	s.invalidateProductCaches(product)
+	s.emit(models.EventProductUpdated, product)
+
+	return product, nil
 }
 
-func (s *ProductService) UpdateProduct(product *models.Product) error {
-	err := s.productRepo.Update(product)
+// ReprocessFailedImages queues processing again for the product's failed
+// images only; images that were processed successfully are left untouched.
+func (s *ProductService) ReprocessFailedImages(actor Actor, id uint) (*models.Product, error) {
+	product, err := s.loadOwnedProduct(actor, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return s.InvalidateCache(product.ID)
+
+	failed := product.ImageVariants.Sources(models.ImageStatusFailed)
+	if len(failed) == 0 {
+		return nil, ErrNoFailedImages
+	}
+
+	results := make([]models.ImageResult, len(failed))
+	for i, source := range failed {
+		results[i] = models.ImageResult{Source: source, Status: models.ImageStatusPending}
+	}
+	messages, err := imageProcessingMessages(product.ID, failed)
+	if err != nil {
+		return nil, err
+	}
+	product, err = s.productRepo.MergeImageResultsWithOutbox(product.ID, results, messages)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateProductCaches(product)
+
+	return product, nil
 }
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ProductCacheConfig tunes how ProductService caches reads. Zero values fall
+// back to the defaults below; stale-while-revalidate is off unless
+// StaleWhileRevalidate is set.
+type ProductCacheConfig struct {
+	// Jitter shortens each TTL by a random fraction of up to Jitter, so keys
+	// cached together don't expire together. A negative Jitter disables it.
+	Jitter float64
+	// StaleWhileRevalidate keeps entries this long past their TTL. A stale
+	// entry is still served while a single background load refreshes it.
+	StaleWhileRevalidate time.Duration
+	// NotFoundTTL is how long a product ID that doesn't exist is remembered.
+	NotFoundTTL time.Duration
+}
+
+const (
+	defaultCacheJitter      = 0.1
+	defaultNotFoundCacheTTL = 30 * time.Second
+)
+
+// CacheEntry is the form in which ProductService caches a value. A product
+// known not to exist is cached as an entry with NotFound set and no value.
+type CacheEntry[T any] struct {
+	Value    T    `json:"value,omitempty"`
+	NotFound bool `json:"not_found,omitempty"`
+	// StaleAt is when the entry stops being fresh. Stale entries are only
+	// served within the stale-while-revalidate window.
+	StaleAt time.Time `json:"stale_at"`
+}
+
+func (e *CacheEntry[T]) result() (T, error) {
+	if e.NotFound {
+		var zero T
+		return zero, ErrProductNotFound
+	}
+	return e.Value, nil
+}
+
+// cachedLoad returns the value cached under key, calling load on a miss.
+// Concurrent misses of a key share one load, as do refreshes of a stale
+// entry, which happen in the background while the stale entry is served.
+// Loaded values are cached for about ttl, tagged with tags; a load failing
+// with ErrProductNotFound is cached for the not-found TTL.
+func cachedLoad[T any](s *ProductService, key string, ttl time.Duration, tags []string, load func() (T, error)) (T, error) {
+	ctx := context.Background()
+
+	var entry CacheEntry[T]
+	err := s.cache.Get(ctx, key, &entry)
+	if err == nil {
+		now := time.Now()
+		if now.Before(entry.StaleAt) {
+			return entry.result()
+		}
+		if now.Before(entry.StaleAt.Add(s.cacheCfg.StaleWhileRevalidate)) {
+			refreshCache(s, key, ttl, tags, load)
+			return entry.result()
+		}
+		// Past the stale window, e.g. a copy outliving Redis in a local tier
+	} else {
+		s.handleCacheError(err, "get")
+	}
+
+	v, err, _ := s.loads.Do(key, func() (interface{}, error) {
+		return fillCache(s, key, ttl, tags, load)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// refreshCache reloads a stale entry in the background, unless a load of
+// key is already running.
+func refreshCache[T any](s *ProductService, key string, ttl time.Duration, tags []string, load func() (T, error)) {
+	s.loads.DoChan(key, func() (interface{}, error) {
+		return fillCache(s, key, ttl, tags, load)
+	})
+}
+
+// fillCache calls load and caches its result.
+func fillCache[T any](s *ProductService, key string, ttl time.Duration, tags []string, load func() (T, error)) (T, error) {
+	value, err := load()
+	if err != nil && !errors.Is(err, ErrProductNotFound) {
+		return value, err
+	}
+	storeCacheEntry(s, key, CacheEntry[T]{Value: value, NotFound: err != nil}, ttl, tags)
+	return value, err
+}
+
+// storeCacheEntry caches entry for about ttl, or for the not-found TTL when
+// it records a missing product, plus the stale-while-revalidate window.
+func storeCacheEntry[T any](s *ProductService, key string, entry CacheEntry[T], ttl time.Duration, tags []string) {
+	if entry.NotFound {
+		ttl = s.cacheCfg.NotFoundTTL
+	}
+	ttl = s.jitter(ttl)
+	entry.StaleAt = time.Now().Add(ttl)
+	expiration := ttl + s.cacheCfg.StaleWhileRevalidate
+
+	ctx := context.Background()
+	var err error
+	if len(tags) > 0 {
+		err = s.cache.SetWithTags(ctx, key, entry, expiration, tags...)
+	} else {
+		err = s.cache.Set(ctx, key, entry, expiration)
+	}
+	if err != nil {
+		s.handleCacheError(err, "set")
+	}
+}
+
+// jitter shortens ttl by a random fraction of up to the configured jitter.
+func (s *ProductService) jitter(ttl time.Duration) time.Duration {
+	spread := int64(float64(ttl) * s.cacheCfg.Jitter)
+	if spread <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(rand.Int63n(spread+1))
+}
@@ -1,19 +1,57 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository interface {
 	Create(user *models.AppUser) error
+	GetByID(id uint) (*models.AppUser, error)
 	GetByEmail(email string) (*models.AppUser, error)
+	List() ([]models.AppUser, error)
+	Update(user *models.AppUser) error
 }
 
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(hash string) (*models.RefreshToken, error)
+	Revoke(id uint) (bool, error)
+	RevokeFamily(familyID string) error
+}
+
+// TokenDenylist stores the IDs of access tokens revoked before they expire.
+type TokenDenylist interface {
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type TokenConfig struct {
+	Secret     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type CreateUserRequest struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
@@ -21,14 +59,29 @@ type CreateUserRequest struct {
 }
 
 type UserService struct {
-	userRepo  UserRepository
-	jwtSecret string
+	userRepo   UserRepository
+	tokenRepo  RefreshTokenRepository
+	denylist   TokenDenylist
+	jwtSecret  string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
-func NewUserService(repo UserRepository, jwtSecret string) *UserService {
+func NewUserService(repo UserRepository, tokenRepo RefreshTokenRepository, denylist TokenDenylist, cfg TokenConfig) *UserService {
+	if cfg.AccessTTL == 0 {
+		cfg.AccessTTL = defaultAccessTokenTTL
+	}
+	if cfg.RefreshTTL == 0 {
+		cfg.RefreshTTL = defaultRefreshTokenTTL
+	}
+
 	return &UserService{
-		userRepo:  repo,
-		jwtSecret: jwtSecret,
+		userRepo:   repo,
+		tokenRepo:  tokenRepo,
+		denylist:   denylist,
+		jwtSecret:  cfg.Secret,
+		accessTTL:  cfg.AccessTTL,
+		refreshTTL: cfg.RefreshTTL,
 	}
 }
 
@@ -52,6 +105,7 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*models.AppUser, error
 		Email:    req.Email,
 		Name:     req.Name,
 		Password: hashedPassword,
+		Role:     models.RoleEditor,
 	}
 
 	// Store the user in the database
@@ -64,16 +118,157 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*models.AppUser, error
 	return user, nil
 }
 
+// GenerateToken issues a short-lived access token. Each token carries a
+// unique jti so it can be revoked on logout.
 func (s *UserService) GenerateToken(user *models.AppUser) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"role":    user.Role,
+		"jti":     uuid.New().String(),
+		"exp":     time.Now().Add(s.accessTTL).Unix(),
 	})
 
 	return token.SignedString([]byte(s.jwtSecret))
 }
 
+// CreateRefreshToken starts a new refresh token family for a fresh login.
+func (s *UserService) CreateRefreshToken(user *models.AppUser) (string, error) {
+	return s.issueRefreshToken(user.ID, uuid.New().String())
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. The presented
+// token is revoked; presenting it again revokes its whole family.
+func (s *UserService) RefreshTokens(refreshToken string) (*TokenPair, error) {
+	stored, err := s.tokenRepo.GetByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, s.revokeReusedFamily(stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.tokenRepo.Revoke(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// A concurrent request rotated the same token first
+		return nil, s.revokeReusedFamily(stored)
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	accessToken, err := s.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+	newRefreshToken, err := s.issueRefreshToken(user.ID, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+// Logout revokes the caller's access token until it expires and, when given,
+// the refresh token family it was issued with.
+func (s *UserService) Logout(userID uint, refreshToken, jti string, expiresAt time.Time) error {
+	if refreshToken != "" {
+		stored, err := s.tokenRepo.GetByHash(hashToken(refreshToken))
+		if err != nil || stored.UserID != userID {
+			return ErrInvalidToken
+		}
+		if err := s.tokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	if jti == "" || s.denylist == nil {
+		return nil
+	}
+	return s.denylist.Revoke(context.Background(), jti, time.Until(expiresAt))
+}
+
+func (s *UserService) issueRefreshToken(userID uint, familyID string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	err := s.tokenRepo.Create(&models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (s *UserService) revokeReusedFamily(stored *models.RefreshToken) error {
+	if err := s.tokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrTokenReused
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListUsers returns all users without their password hashes.
+func (s *UserService) ListUsers() ([]models.AppUser, error) {
+	users, err := s.userRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+	return users, nil
+}
+
+// AssignRole changes a user's role. The new role is picked up by the user's
+// next access token. Admins cannot change their own role, so the last admin
+// cannot lock everyone out.
+func (s *UserService) AssignRole(actor Actor, userID uint, role string) (*models.AppUser, error) {
+	if !actor.Admin {
+		return nil, ErrForbidden
+	}
+	if !models.ValidRole(role) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+	if actor.UserID == userID {
+		return nil, fmt.Errorf("%w: cannot change your own role", ErrForbidden)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.Role = role
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
 func (s *UserService) ValidateCredentials(email, password string) (*models.AppUser, error) {
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventEmitter notifies subscribers of product lifecycle events.
+type EventEmitter interface {
+	Emit(userID uint, eventType string, data interface{}) error
+}
+
+type WebhookRepository interface {
+	CreateSubscription(subscription *models.WebhookSubscription) error
+	GetSubscription(id uint) (*models.WebhookSubscription, error)
+	ListSubscriptions(userID uint) ([]models.WebhookSubscription, error)
+	ListSubscribers(userID uint, eventType string) ([]models.WebhookSubscription, error)
+	DeleteSubscription(id uint) error
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+	ListDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error)
+}
+
+// WebhookConfig tunes delivery. Zero values fall back to the defaults below.
+type WebhookConfig struct {
+	// MaxAttempts is the number of attempts before a delivery fails.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubling after
+	// each further failure up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds a single attempt.
+	Timeout time.Duration
+	// BatchSize is the number of deliveries claimed at once.
+	BatchSize int
+	// PollInterval is how often the worker looks for due deliveries.
+	PollInterval time.Duration
+	// AllowPrivateTargets lets subscriptions reach loopback, private and
+	// link-local addresses, e.g. a receiver on the same machine during
+	// development. They are refused otherwise.
+	AllowPrivateTargets bool
+}
+
+const (
+	defaultWebhookMaxAttempts  = 8
+	defaultWebhookBackoff      = 30 * time.Second
+	defaultWebhookMaxBackoff   = time.Hour
+	defaultWebhookTimeout      = 10 * time.Second
+	defaultWebhookBatchSize    = 20
+	defaultWebhookPollInterval = 5 * time.Second
+
+	maxWebhookErrorLength = 500
+)
+
+// Headers sent with every webhook delivery. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderID        = "X-Webhook-ID"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookEvent is the body of a webhook delivery.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs deliveries; a random one is generated when empty.
+	Secret string `json:"secret"`
+}
+
+// CreatedWebhook is a new subscription together with its secret, which is
+// not shown again.
+type CreatedWebhook struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+type WebhookService struct {
+	repo   WebhookRepository
+	client *http.Client
+	cfg    WebhookConfig
+}
+
+func NewWebhookService(repo WebhookRepository, cfg WebhookConfig) *WebhookService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultWebhookBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhookBatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultWebhookPollInterval
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cfg.AllowPrivateTargets {
+		// Checked on the resolved address of every connection, redirects
+		// included, so a name can't be pointed at an internal address
+		// after it was validated. A proxy would be checked instead of the
+		// target, so none is used.
+		dialer := &net.Dialer{Timeout: cfg.Timeout, Control: refusePrivateTargets}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+
+	return &WebhookService{
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		cfg:    cfg,
+	}
+}
+
+func (s *WebhookService) CreateSubscription(actor Actor, req *CreateWebhookRequest) (*CreatedWebhook, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if !s.cfg.AllowPrivateTargets && privateHost(target.Hostname()) {
+		return nil, fmt.Errorf("%w: url must not point at a private or local address", ErrInvalidWebhook)
+	}
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for _, event := range req.Events {
+		if !models.ValidWebhookEvent(event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &models.WebhookSubscription{
+		UserID: actor.UserID,
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: secret,
+		Active: true,
+	}
+	if err := s.repo.CreateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return &CreatedWebhook{WebhookSubscription: *subscription, Secret: secret}, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *WebhookService) ListSubscriptions(actor Actor) ([]models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(actor.UserID)
+}
+
+func (s *WebhookService) GetSubscription(actor Actor, id uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.repo.GetSubscription(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanAccess(subscription.UserID) {
+		return nil, ErrForbidden
+	}
+	return subscription, nil
+}
+
+func (s *WebhookService) DeleteSubscription(actor Actor, id uint) error {
+	if _, err := s.GetSubscription(actor, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(id)
+}
+
+func (s *WebhookService) ListDeliveries(actor Actor, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(actor, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(subscriptionID, limit)
+}
+
+// Emit queues a delivery of the event to each of the user's subscriptions
+// to its type. Deliveries are sent by Run.
+func (s *WebhookService) Emit(userID uint, eventType string, data interface{}) error {
+	subscriptions, err := s.repo.ListSubscribers(userID, eventType)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	event := WebhookEvent{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  event.CreatedAt,
+		}
+	}
+	return s.repo.CreateDeliveries(deliveries)
+}
+
+// Run sends due deliveries until ctx is done.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while there is a backlog
+		for {
+			n, err := s.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("Failed to deliver webhooks: %v", err)
+			}
+			if err != nil || n < s.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many it sent.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	// Claims outlive an attempt so no other worker picks the delivery up
+	// while it is being sent
+	deliveries, err := s.repo.ClaimDueDeliveries(s.cfg.BatchSize, 2*s.cfg.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		// Deliveries left unsent are picked up again once their claim expires
+		if !s.deliver(ctx, &deliveries[i]) {
+			continue
+		}
+		if err := s.repo.UpdateDelivery(&deliveries[i]); err != nil {
+			log.Printf("Failed to record webhook delivery %d: %v", deliveries[i].ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt at a delivery and records its outcome: success,
+// another attempt after the backoff, or failure once out of attempts. It
+// reports false when ctx ended the attempt, which then doesn't count.
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	status, err := s.send(ctx, delivery)
+	if err != nil && ctx.Err() != nil {
+		return false
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+
+	now := time.Now()
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return true
+	}
+
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > maxWebhookErrorLength {
+		delivery.LastError = delivery.LastError[:maxWebhookErrorLength]
+	}
+	if delivery.Attempts >= s.cfg.MaxAttempts || !delivery.Subscription.Active {
+		delivery.Status = models.WebhookDeliveryFailed
+		return true
+	}
+	delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+	return true
+}
+
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.cfg.Backoff
+	for i := 1; i < attempts && delay < s.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.cfg.MaxBackoff)
+}
+
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	if !delivery.Subscription.Active {
+		return 0, errors.New("subscription is inactive")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "product-management-system-webhooks")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderID, delivery.EventID)
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhook(delivery.Subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// errPrivateTarget is returned when a delivery would connect to an address
+// subscriptions may not reach.
+var errPrivateTarget = errors.New("webhook target resolves to a private or local address")
+
+// nonPublicNetworks are reserved ranges not covered by the net.IP checks in
+// privateIP.
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// privateIP reports whether ip is a loopback, private, link-local (such as
+// the 169.254.169.254 metadata service) or otherwise non-public address.
+func privateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateHost reports whether host is a private address or a name that
+// always refers to the local machine. Other names are checked once
+// resolved, when connecting.
+func privateHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return privateIP(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// refusePrivateTargets is a net.Dialer Control function failing connections
+// to private addresses.
+func refusePrivateTargets(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+		return errPrivateTarget
+	}
+	return nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 signature of a delivery body sent
+// at timestamp. Receivers recompute it to verify a delivery and should
+// reject old timestamps to prevent replays.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
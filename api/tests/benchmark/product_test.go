@@ -28,14 +28,15 @@ func setupBenchmarkEnvironment(b *testing.B) (*services.ProductService, *postgre
 	}
 
 	// Initialize Redis cache
-	redisCache, err := cache.NewRedisCache("localhost:6379", "")
+	redisCache, err := cache.NewRedisCache(cache.RedisConfig{Addrs: []string{"localhost:6379"}})
 	if err != nil {
 		b.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
 	// Initialize repository and service
 	repo := postgres.NewProductRepository(db)
-	service := services.NewProductService(repo, nil, redisCache)
+	postgres.NewOutboxRepository(db)
+	service := services.NewProductService(repo, redisCache, nil, nil, services.ProductCacheConfig{})
 
 	return service, repo, redisCache
 }
@@ -69,7 +70,7 @@ func BenchmarkGetProduct(b *testing.B) {
 	b.Run("With Cache", func(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			service.GetProduct(1)
+			service.GetProduct(services.Actor{UserID: 1}, 1)
 		}
 	})
 
@@ -94,7 +95,7 @@ func BenchmarkCreateProduct(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		service.CreateProduct(req)
+		service.CreateProduct(services.Actor{UserID: 1}, req)
 	}
 }
 
@@ -112,7 +113,7 @@ func BenchmarkGetFilteredProducts(b *testing.B) {
 
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			service.GetFilteredProducts(req)
+			service.GetFilteredProducts(services.Actor{UserID: 1}, req)
 		}
 	})
 
@@ -126,7 +127,7 @@ func BenchmarkGetFilteredProducts(b *testing.B) {
 
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			service.GetFilteredProducts(req)
+			service.GetFilteredProducts(services.Actor{UserID: 1}, req)
 		}
 	})
 }
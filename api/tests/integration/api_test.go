@@ -18,10 +18,10 @@ import (
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/api/repository/postgres"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/database"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
 )
 
@@ -33,6 +33,7 @@ type IntegrationTestSuite struct {
 
 type TestCache struct {
 	data map[string][]byte
+	tags map[string][]string
 	mu   sync.RWMutex
 }
 
@@ -43,7 +44,7 @@ func (c *TestCache) Get(ctx context.Context, key string, dest interface{}) error
 	if data, ok := c.data[key]; ok {
 		return json.Unmarshal(data, dest)
 	}
-	return fmt.Errorf("cache miss")
+	return cache.ErrMiss
 }
 
 func (c *TestCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
@@ -65,6 +66,41 @@ func (c *TestCache) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+func (c *TestCache) BatchGetInto(ctx context.Context, dests map[string]interface{}) ([]string, error) {
+	var missing []string
+	for key, dest := range dests {
+		if err := c.Get(ctx, key, dest); err != nil {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
+
+func (c *TestCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	if err := c.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		c.tags[tag] = append(c.tags[tag], key)
+	}
+	return nil
+}
+
+func (c *TestCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for _, key := range c.tags[tag] {
+			delete(c.data, key)
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
 func setupIntegrationTest(t *testing.T) *IntegrationTestSuite {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -110,6 +146,7 @@ func setupIntegrationTest(t *testing.T) *IntegrationTestSuite {
 
 func (s *IntegrationTestSuite) cleanup() {
 	// Clean up test data
+	s.db.Exec("DELETE FROM app_outbox")
 	s.db.Exec("DELETE FROM app_products")
 	s.db.Exec("DELETE FROM app_users")
 }
@@ -121,24 +158,23 @@ func setupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	// Initialize repositories
 	userRepo := postgres.NewUserRepository(db)
 	productRepo := postgres.NewProductRepository(db)
-
-	// Initialize mock message queue publisher
-	mockPublisher := &MockPublisher{
-		mock.Mock{},
-	}
+	// Image processing tasks are written to the outbox and left unsent
+	postgres.NewOutboxRepository(db)
 
 	// Initialize test cache
 	testCache := &TestCache{
 		data: make(map[string][]byte),
+		tags: make(map[string][]string),
 	}
 
 	// Initialize services with mocks
-	userService := services.NewUserService(userRepo, "test-secret")
-	productService := services.NewProductService(productRepo, mockPublisher, testCache)
+	userService := services.NewUserService(userRepo, postgres.NewRefreshTokenRepository(db), nil,
+		services.TokenConfig{Secret: "test-secret"})
+	productService := services.NewProductService(productRepo, testCache, nil, nil, services.ProductCacheConfig{})
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(userService)
-	productHandler := handlers.NewProductHandler(productService)
+	productHandler := handlers.NewProductHandler(productService, handlers.UploadConfig{})
 
 	// Setup routes
 	api := router.Group("/api")
@@ -147,31 +183,25 @@ func setupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
 		}
 
 		products := api.Group("/products")
-		products.Use(middleware.AuthMiddleware("test-secret"))
+		products.Use(middleware.AuthMiddleware("test-secret", nil))
 		{
 			products.POST("", productHandler.CreateProduct)
 			products.GET("/:id", productHandler.GetProduct)
 			products.GET("", productHandler.GetUserProducts)
 			products.GET("/filter", productHandler.GetFilteredProducts)
+			products.PUT("/:id", productHandler.UpdateProduct)
+			products.PATCH("/:id", productHandler.PatchProduct)
+			products.DELETE("/:id", productHandler.DeleteProduct)
 		}
 	}
 
 	return router
 }
 
-// Add MockPublisher implementation
-type MockPublisher struct {
-	mock.Mock
-}
-
-func (m *MockPublisher) Publish(queue string, data []byte) error {
-	args := m.Called(queue, data)
-	return args.Error(0)
-}
-
 func TestUserRegistrationAndAuthentication(t *testing.T) {
 	suite := setupIntegrationTest(t)
 	defer suite.cleanup()
@@ -290,12 +320,13 @@ func TestProductOperations(t *testing.T) {
 		)
 		assert.Equal(t, http.StatusOK, w.Code)
 
-		var filteredProducts []models.Product
-		err := json.Unmarshal(w.Body.Bytes(), &filteredProducts)
+		var page services.ProductPage
+		err := json.Unmarshal(w.Body.Bytes(), &page)
 		assert.NoError(t, err)
-		assert.NotEmpty(t, filteredProducts)
+		assert.NotEmpty(t, page.Items)
+		assert.Equal(t, int64(len(page.Items)), page.Total)
 
-		for _, p := range filteredProducts {
+		for _, p := range page.Items {
 			assert.Equal(t, user.ID, p.UserID)
 			assert.True(t, p.ProductPrice >= 50 && p.ProductPrice <= 150)
 			assert.Contains(t, p.ProductName, "Test")
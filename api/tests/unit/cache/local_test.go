@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := cache.NewLocalCache(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+
+	// Reading a makes b the least recently used
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	c.Set("c", []byte("3"), 0)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	data, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), data)
+	assert.Equal(t, 2, c.Len())
+
+	t.Run("Overwrite Keeps Size", func(t *testing.T) {
+		c.Set("a", []byte("4"), 0)
+		data, _ := c.Get("a")
+		assert.Equal(t, []byte("4"), data)
+		assert.Equal(t, 2, c.Len())
+	})
+}
+
+func TestLocalCacheExpires(t *testing.T) {
+	c := cache.NewLocalCache(10)
+	c.Set("a", []byte("1"), 20*time.Millisecond)
+	c.Set("b", []byte("2"), time.Hour)
+
+	time.Sleep(40 * time.Millisecond)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestLocalCacheDeleteAndPurge(t *testing.T) {
+	c := cache.NewLocalCache(10)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+	c.Set("c", []byte("3"), 0)
+
+	c.Delete("a", "b", "missing")
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+
+	c.Purge()
+	_, ok = c.Get("c")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
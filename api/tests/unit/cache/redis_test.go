@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisConfigValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  cache.RedisConfig
+	}{
+		{"No Addresses", cache.RedisConfig{}},
+		{"Standalone With Several Addresses", cache.RedisConfig{Addrs: []string{"a:6379", "b:6379"}}},
+		{"Sentinel Without Master", cache.RedisConfig{Mode: cache.ModeSentinel, Addrs: []string{"a:26379"}}},
+		{"Cluster With DB", cache.RedisConfig{Mode: cache.ModeCluster, Addrs: []string{"a:6379"}, DB: 1}},
+		{"Unknown Mode", cache.RedisConfig{Mode: "replicated", Addrs: []string{"a:6379"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cache.NewRedisCache(tt.cfg)
+			assert.ErrorIs(t, err, cache.ErrInvalidRedisConfig)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// api/tests/unit/handlers/admin_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/handlers"
+	"github.com/KPVISHNUSAI/product-management-system/api/middleware"
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserAdminService struct {
+	mock.Mock
+}
+
+func (m *MockUserAdminService) ListUsers() ([]models.AppUser, error) {
+	args := m.Called()
+	return args.Get(0).([]models.AppUser), args.Error(1)
+}
+
+func (m *MockUserAdminService) AssignRole(actor services.Actor, userID uint, role string) (*models.AppUser, error) {
+	args := m.Called(actor, userID, role)
+	return args.Get(0).(*models.AppUser), args.Error(1)
+}
+
+// setupAdminTestRouter authenticates every request as user 1 with role.
+func setupAdminTestRouter(role string) (*gin.Engine, *MockUserAdminService) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("role", role)
+		c.Next()
+	})
+	mockService := new(MockUserAdminService)
+	handler := handlers.NewAdminHandler(mockService)
+
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/users", handler.ListUsers)
+		admin.PUT("/users/:id/role", handler.AssignRole)
+	}
+
+	return router, mockService
+}
+
+func TestAssignRole(t *testing.T) {
+	t.Run("Successful Assignment", func(t *testing.T) {
+		router, mockService := setupAdminTestRouter(models.RoleAdmin)
+		actor := services.Actor{UserID: 1, Admin: true}
+
+		mockService.On("AssignRole", actor, uint(2), models.RoleViewer).
+			Return(&models.AppUser{ID: 2, Role: models.RoleViewer}, nil)
+
+		body, _ := json.Marshal(map[string]string{"role": models.RoleViewer})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/api/admin/users/2/role", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.AppUser
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, models.RoleViewer, response.Role)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Role", func(t *testing.T) {
+		router, mockService := setupAdminTestRouter(models.RoleAdmin)
+
+		mockService.On("AssignRole", mock.Anything, uint(2), "superuser").
+			Return((*models.AppUser)(nil), services.ErrInvalidRole)
+
+		body, _ := json.Marshal(map[string]string{"role": "superuser"})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/api/admin/users/2/role", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Non Admin Rejected", func(t *testing.T) {
+		router, mockService := setupAdminTestRouter(models.RoleEditor)
+
+		body, _ := json.Marshal(map[string]string{"role": models.RoleAdmin})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/api/admin/users/1/role", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestListUsers(t *testing.T) {
+	router, mockService := setupAdminTestRouter(models.RoleAdmin)
+
+	mockService.On("ListUsers").Return([]models.AppUser{{ID: 1}, {ID: 2}}, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/admin/users", nil)
+
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []models.AppUser
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(t, response, 2)
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/handlers"
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
@@ -37,6 +38,21 @@ func (m *MockUserService) GenerateToken(user *models.AppUser) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockUserService) CreateRefreshToken(user *models.AppUser) (string, error) {
+	args := m.Called(user)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserService) RefreshTokens(refreshToken string) (*services.TokenPair, error) {
+	args := m.Called(refreshToken)
+	return args.Get(0).(*services.TokenPair), args.Error(1)
+}
+
+func (m *MockUserService) Logout(userID uint, refreshToken, jti string, expiresAt time.Time) error {
+	args := m.Called(userID, refreshToken, jti, expiresAt)
+	return args.Error(0)
+}
+
 func setupAuthTestRouter() (*gin.Engine, *MockUserService) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -47,6 +63,7 @@ func setupAuthTestRouter() (*gin.Engine, *MockUserService) {
 	{
 		auth.POST("/register", handler.Register)
 		auth.POST("/login", handler.Login)
+		auth.POST("/refresh", handler.Refresh)
 	}
 
 	return router, mockService
@@ -142,6 +159,7 @@ func TestLogin(t *testing.T) {
 
 		mockService.On("ValidateCredentials", req.Email, req.Password).Return(user, nil)
 		mockService.On("GenerateToken", user).Return(expectedToken, nil)
+		mockService.On("CreateRefreshToken", user).Return("refresh-token", nil)
 
 		body, _ := json.Marshal(req)
 		w := httptest.NewRecorder()
@@ -155,6 +173,7 @@ func TestLogin(t *testing.T) {
 		var response map[string]string
 		json.Unmarshal(w.Body.Bytes(), &response)
 		assert.Equal(t, expectedToken, response["token"])
+		assert.Equal(t, "refresh-token", response["refresh_token"])
 		mockService.AssertExpectations(t)
 	})
 
@@ -233,5 +252,78 @@ func TestLogin(t *testing.T) {
 		// Verify mock expectations
 		mockService.AssertExpectations(t)
 	})
+}
+
+func TestRefresh(t *testing.T) {
+	router, mockService := setupAuthTestRouter()
+
+	t.Run("Successful Refresh", func(t *testing.T) {
+		tokens := &services.TokenPair{AccessToken: "new.access.token", RefreshToken: "new-refresh"}
+		mockService.On("RefreshTokens", "old-refresh").Return(tokens, nil)
+
+		body, _ := json.Marshal(map[string]string{"refresh_token": "old-refresh"})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/auth/refresh", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, tokens.AccessToken, response["token"])
+		assert.Equal(t, tokens.RefreshToken, response["refresh_token"])
+	})
+
+	t.Run("Reused Refresh Token", func(t *testing.T) {
+		mockService.On("RefreshTokens", "reused-refresh").
+			Return((*services.TokenPair)(nil), services.ErrTokenReused)
+
+		body, _ := json.Marshal(map[string]string{"refresh_token": "reused-refresh"})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/auth/refresh", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Missing Refresh Token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/auth/refresh", bytes.NewBuffer([]byte("{}")))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mockService := new(MockUserService)
+	handler := handlers.NewAuthHandler(mockService)
+
+	expiresAt := time.Unix(1700000000, 0)
+	router.POST("/api/auth/logout", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("jti", "access-jti")
+		c.Set("token_expires_at", expiresAt)
+		c.Next()
+	}, handler.Logout)
+
+	mockService.On("Logout", uint(1), "refresh", "access-jti", expiresAt).Return(nil)
+
+	body, _ := json.Marshal(map[string]string{"refresh_token": "refresh"})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/api/auth/logout", bytes.NewBuffer(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, r)
 
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
 }
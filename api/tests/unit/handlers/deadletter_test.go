@@ -0,0 +1,157 @@
+// api/tests/unit/handlers/deadletter_test.go
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/handlers"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeadLetterService struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterService) ListDeadLetters(filter services.DeadLetterFilter, limit int) ([]messaging.DeadLetter, error) {
+	args := m.Called(filter, limit)
+	return args.Get(0).([]messaging.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterService) GetDeadLetter(id string) (*messaging.DeadLetter, error) {
+	args := m.Called(id)
+	return args.Get(0).(*messaging.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterService) PurgeDeadLetters(filter services.DeadLetterFilter) (int, error) {
+	args := m.Called(filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDeadLetterService) ReplayDeadLetters(filter services.DeadLetterFilter) ([]messaging.DeadLetter, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]messaging.DeadLetter), args.Error(1)
+}
+
+func setupDeadLetterRouter() (*gin.Engine, *MockDeadLetterService) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mockService := new(MockDeadLetterService)
+	handler := handlers.NewDeadLetterHandler(mockService)
+
+	admin := router.Group("/api/admin")
+	{
+		admin.GET("/dlq", handler.ListDeadLetters)
+		admin.GET("/dlq/:id", handler.GetDeadLetter)
+		admin.POST("/dlq/:id/replay", handler.ReplayDeadLetter)
+		admin.DELETE("/dlq/:id", handler.DeleteDeadLetter)
+		admin.POST("/dlq/replay", handler.ReplayDeadLetters)
+		admin.POST("/dlq/purge", handler.PurgeDeadLetters)
+	}
+
+	return router, mockService
+}
+
+func TestListDeadLetters(t *testing.T) {
+	router, mockService := setupDeadLetterRouter()
+
+	mockService.On("ListDeadLetters", services.DeadLetterFilter{ProductID: 4, ErrorClass: "timeout"}, 100).
+		Return([]messaging.DeadLetter{{ID: "a", ProductID: 4, ErrorClass: "timeout"}}, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/admin/dlq?product_id=4&error_class=timeout", nil)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []messaging.DeadLetter
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "a", response[0].ID)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/api/admin/dlq?product_id=x", nil)
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetDeadLetterHandler(t *testing.T) {
+	router, mockService := setupDeadLetterRouter()
+
+	mockService.On("GetDeadLetter", "missing").Return((*messaging.DeadLetter)(nil), services.ErrDeadLetterNotFound)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/admin/dlq/missing", nil)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReplayDeadLettersHandler(t *testing.T) {
+	router, mockService := setupDeadLetterRouter()
+
+	t.Run("Replay One", func(t *testing.T) {
+		mockService.On("ReplayDeadLetters", services.DeadLetterFilter{IDs: []string{"a"}}).
+			Return([]messaging.DeadLetter{{ID: "a"}}, nil)
+		mockService.On("ReplayDeadLetters", services.DeadLetterFilter{IDs: []string{"gone"}}).
+			Return([]messaging.DeadLetter{}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/dlq/a/replay", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/dlq/gone/replay", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Replay By Filter", func(t *testing.T) {
+		mockService.On("ReplayDeadLetters", services.DeadLetterFilter{ProductID: 7}).
+			Return([]messaging.DeadLetter{{ID: "b", ProductID: 7}}, nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"product_id": 7})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/admin/dlq/replay", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"replayed"`)
+	})
+
+	t.Run("Empty Filter Rejected", func(t *testing.T) {
+		mockService.On("ReplayDeadLetters", services.DeadLetterFilter{}).
+			Return([]messaging.DeadLetter(nil), services.ErrInvalidFilter)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/admin/dlq/replay", bytes.NewBufferString(`{}`))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPurgeDeadLettersHandler(t *testing.T) {
+	router, mockService := setupDeadLetterRouter()
+
+	mockService.On("PurgeDeadLetters", services.DeadLetterFilter{IDs: []string{"a"}}).Return(1, nil)
+	mockService.On("PurgeDeadLetters", services.DeadLetterFilter{ErrorClass: "invalid_image"}).Return(3, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/admin/dlq/a", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/api/admin/dlq/purge", bytes.NewBufferString(`{"error_class":"invalid_image"}`))
+	r.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"purged":3}`, w.Body.String())
+}
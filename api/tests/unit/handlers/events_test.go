@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/handlers"
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockProcessingEventService struct {
+	mock.Mock
+}
+
+func (m *MockProcessingEventService) LastProcessingEvent(ctx context.Context, productID uint) (string, error) {
+	args := m.Called(productID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockProcessingEventService) ReadProcessingEvents(ctx context.Context, productID uint, after string, wait time.Duration) ([]services.ProcessingEvent, error) {
+	args := m.Called(productID, after)
+	return args.Get(0).([]services.ProcessingEvent), args.Error(1)
+}
+
+func setupEventsRouter() (*gin.Engine, *MockProductService, *MockProcessingEventService) {
+	return setupEventsRouterWithConfig(handlers.EventsConfig{Heartbeat: time.Millisecond})
+}
+
+func setupEventsRouterWithConfig(cfg handlers.EventsConfig) (*gin.Engine, *MockProductService, *MockProcessingEventService) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", testActor.UserID)
+		c.Next()
+	})
+	mockService := new(MockProductService)
+	mockEvents := new(MockProcessingEventService)
+	handler := handlers.NewProductEventsHandler(mockService, mockEvents, cfg)
+
+	router.GET("/api/products/:id/events", handler.StreamProductEvents)
+	return router, mockService, mockEvents
+}
+
+// errStreamClosed ends a stream under test once its events are read.
+var errStreamClosed = errors.New("stream closed")
+
+func TestStreamProductEvents(t *testing.T) {
+	t.Run("Starts With Current Status", func(t *testing.T) {
+		router, mockService, mockEvents := setupEventsRouter()
+		mockEvents.On("LastProcessingEvent", uint(1)).Return("5-0", nil)
+		mockService.On("GetProduct", testActor, uint(1)).
+			Return(&models.Product{ID: 1, ProcessingStatus: models.ProcessingStatusProcessing}, nil)
+		mockEvents.On("ReadProcessingEvents", uint(1), "5-0").Return([]services.ProcessingEvent{}, nil).Once()
+		mockEvents.On("ReadProcessingEvents", uint(1), "5-0").Return([]services.ProcessingEvent{
+			{ID: "6-0", ProductID: 1, ProcessingStatus: models.ProcessingStatusCompleted},
+		}, nil).Once()
+		mockEvents.On("ReadProcessingEvents", uint(1), "6-0").Return([]services.ProcessingEvent{}, errStreamClosed)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/1/events", nil)
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		body := w.Body.String()
+		assert.Contains(t, body, "id: 5-0\nevent: processing_status\ndata: {\"product_id\":1,\"processing_status\":\"processing\"")
+		assert.Contains(t, body, ": heartbeat\n\n")
+		assert.Contains(t, body, "id: 6-0\nevent: processing_status\ndata: {\"product_id\":1,\"processing_status\":\"completed\"")
+	})
+
+	t.Run("Resumes After Last Event ID", func(t *testing.T) {
+		router, mockService, mockEvents := setupEventsRouter()
+		mockService.On("GetProduct", testActor, uint(1)).Return(&models.Product{ID: 1}, nil)
+		mockEvents.On("ReadProcessingEvents", uint(1), "6-0").Return([]services.ProcessingEvent{}, errStreamClosed)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/1/events", nil)
+		r.Header.Set("Last-Event-ID", "6-0")
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "event:")
+		mockEvents.AssertNotCalled(t, "LastProcessingEvent", mock.Anything)
+	})
+
+	t.Run("Invalid Last Event ID", func(t *testing.T) {
+		router, _, _ := setupEventsRouter()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/1/events", nil)
+		r.Header.Set("Last-Event-ID", "$")
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Turns Away Streams Over The Limit", func(t *testing.T) {
+		router, mockService, mockEvents := setupEventsRouterWithConfig(handlers.EventsConfig{Heartbeat: time.Millisecond, MaxStreams: 1})
+		reading := make(chan struct{}, 2)
+		release := make(chan struct{})
+		mockEvents.On("LastProcessingEvent", uint(1)).Return("5-0", nil)
+		mockService.On("GetProduct", testActor, uint(1)).Return(&models.Product{ID: 1}, nil)
+		mockEvents.On("ReadProcessingEvents", uint(1), "5-0").Run(func(mock.Arguments) {
+			reading <- struct{}{}
+			<-release
+		}).Return([]services.ProcessingEvent{}, errStreamClosed)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/products/1/events", nil))
+		}()
+		<-reading
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/products/1/events", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		// The slot is free again once the first stream ends
+		close(release)
+		<-done
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/products/1/events", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		router, mockService, mockEvents := setupEventsRouter()
+		mockEvents.On("LastProcessingEvent", uint(2)).Return("0", nil)
+		mockService.On("GetProduct", testActor, uint(2)).Return((*models.Product)(nil), services.ErrForbidden)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/2/events", nil)
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockEvents.AssertNotCalled(t, "ReadProcessingEvents", mock.Anything, mock.Anything)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -21,39 +22,78 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductService) CreateProduct(req *services.CreateProductRequest) (*models.Product, error) {
-	args := m.Called(req)
+func (m *MockProductService) CreateProduct(actor services.Actor, req *services.CreateProductRequest) (*models.Product, error) {
+	args := m.Called(actor, req)
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
-func (m *MockProductService) GetProduct(id uint) (*models.Product, error) {
-	args := m.Called(id)
+func (m *MockProductService) GetProduct(actor services.Actor, id uint) (*models.Product, error) {
+	args := m.Called(actor, id)
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
-func (m *MockProductService) GetUserProducts(userID uint) ([]models.Product, error) {
-	args := m.Called(userID)
-	return args.Get(0).([]models.Product), args.Error(1)
+func (m *MockProductService) GetProducts(actor services.Actor, ids []uint) ([]services.ProductLookup, error) {
+	args := m.Called(actor, ids)
+	return args.Get(0).([]services.ProductLookup), args.Error(1)
 }
 
-func (m *MockProductService) GetFilteredProducts(req *services.FilterProductsRequest) ([]models.Product, error) {
-	args := m.Called(req)
-	return args.Get(0).([]models.Product), args.Error(1)
+func (m *MockProductService) GetFilteredProducts(actor services.Actor, req *services.FilterProductsRequest) (*services.ProductPage, error) {
+	args := m.Called(actor, req)
+	return args.Get(0).(*services.ProductPage), args.Error(1)
+}
+
+func (m *MockProductService) ReplaceProduct(actor services.Actor, id uint, req *services.UpdateProductRequest) (*models.Product, error) {
+	args := m.Called(actor, id, req)
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductService) PatchProduct(actor services.Actor, id uint, patch []byte) (*models.Product, error) {
+	args := m.Called(actor, id, patch)
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductService) DeleteProduct(actor services.Actor, id uint) error {
+	args := m.Called(actor, id)
+	return args.Error(0)
+}
+
+// testActor is the principal every request in these tests is authenticated as.
+var testActor = services.Actor{UserID: 1}
+
+func (m *MockProductService) AddProductImages(actor services.Actor, id uint, images []services.UploadedImage) (*models.Product, error) {
+	args := m.Called(actor, id, images)
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductService) ReprocessFailedImages(actor services.Actor, id uint) (*models.Product, error) {
+	args := m.Called(actor, id)
+	return args.Get(0).(*models.Product), args.Error(1)
 }
 
 func setupTestRouter() (*gin.Engine, *MockProductService) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", testActor.UserID)
+		c.Next()
+	})
 	mockService := new(MockProductService)
-	handler := handlers.NewProductHandler(mockService)
+	handler := handlers.NewProductHandler(mockService, handlers.UploadConfig{MaxImageBytes: 1024, MaxImages: 2})
 
 	// Setup routes
 	products := router.Group("/api/products")
 	{
 		products.POST("/", handler.CreateProduct)
 		products.GET("/:id", handler.GetProduct)
+		products.POST("/batch", handler.GetProductsBatch)
 		products.GET("/", handler.GetUserProducts)
 		products.GET("/filter", handler.GetFilteredProducts)
+		products.PUT("/:id", handler.UpdateProduct)
+		products.PATCH("/:id", handler.PatchProduct)
+		products.DELETE("/:id", handler.DeleteProduct)
+		products.POST("/:id/images", handler.UploadProductImages)
+		products.POST("/:id/images/reprocess", handler.ReprocessProductImages)
+		products.GET("/:id/images/:index/:variant", handler.GetProductImage)
 	}
 
 	return router, mockService
@@ -79,7 +119,7 @@ func TestCreateProduct(t *testing.T) {
 			ProductPrice:       req.Price,
 		}
 
-		mockService.On("CreateProduct", &req).Return(expectedProduct, nil)
+		mockService.On("CreateProduct", testActor, &req).Return(expectedProduct, nil)
 
 		body, _ := json.Marshal(req)
 		w := httptest.NewRecorder()
@@ -117,7 +157,7 @@ func TestGetProduct(t *testing.T) {
 			ProductName: "Test Product",
 		}
 
-		mockService.On("GetProduct", uint(1)).Return(product, nil)
+		mockService.On("GetProduct", testActor, uint(1)).Return(product, nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("GET", "/api/products/1", nil)
@@ -133,7 +173,7 @@ func TestGetProduct(t *testing.T) {
 	})
 
 	t.Run("Product Not Found", func(t *testing.T) {
-		mockService.On("GetProduct", uint(999)).Return((*models.Product)(nil),
+		mockService.On("GetProduct", testActor, uint(999)).Return((*models.Product)(nil),
 			fmt.Errorf("not found"))
 
 		w := httptest.NewRecorder()
@@ -143,6 +183,130 @@ func TestGetProduct(t *testing.T) {
 
 		assert.Equal(t, http.StatusNotFound, w.Code)
 	})
+
+	t.Run("Product Owned By Another User", func(t *testing.T) {
+		mockService.On("GetProduct", testActor, uint(2)).Return((*models.Product)(nil),
+			services.ErrForbidden)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/2", nil)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestGetProductsBatch(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	t.Run("Per ID Results", func(t *testing.T) {
+		results := []services.ProductLookup{
+			{ID: 1, Status: services.LookupOK, Product: &models.Product{
+				ID:     1,
+				UserID: 1,
+				User:   models.AppUser{ID: 1, Email: "owner@example.com", Password: "$2a$10$hash"},
+			}},
+			{ID: 2, Status: services.LookupNotFound},
+			{ID: 3, Status: services.LookupForbidden},
+		}
+		mockService.On("GetProducts", testActor, []uint{1, 2, 3}).Return(results, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/batch", bytes.NewBufferString(`{"ids":[1,2,3]}`))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "$2a$10$hash")
+		var response struct {
+			Results []services.ProductLookup `json:"results"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Len(t, response.Results, 3)
+		assert.Equal(t, services.LookupOK, response.Results[0].Status)
+		assert.Equal(t, uint(1), response.Results[0].Product.ID)
+		assert.Equal(t, services.LookupNotFound, response.Results[1].Status)
+		assert.Nil(t, response.Results[1].Product)
+		assert.Equal(t, services.LookupForbidden, response.Results[2].Status)
+	})
+
+	t.Run("Invalid Batch", func(t *testing.T) {
+		mockService.On("GetProducts", testActor, []uint{}).Return([]services.ProductLookup(nil), services.ErrInvalidBatch)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/batch", bytes.NewBufferString(`{"ids":[]}`))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Missing IDs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/batch", bytes.NewBufferString(`{}`))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestGetProductImage(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	mockService.On("GetProduct", testActor, uint(1)).Return(&models.Product{
+		ID: 1,
+		ImageVariants: models.ImageVariants{{
+			Source: "http://example.com/a.jpg",
+			Variants: []models.ImageVariant{{
+				Name:   "thumbnail",
+				URL:    "http://cdn/thumbnail.jpeg",
+				Format: "jpeg",
+				Formats: []models.ImageFile{
+					{Format: "jpeg", URL: "http://cdn/thumbnail.jpeg", Size: 900},
+					{Format: "webp", URL: "http://cdn/thumbnail.webp", Size: 600},
+				},
+			}},
+		}},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		code     int
+		location string
+	}{
+		{"Smallest Accepted Format", "/api/products/1/images/0/thumbnail", "image/webp,image/*;q=0.8", http.StatusFound, "http://cdn/thumbnail.webp"},
+		{"WebP Not Accepted", "/api/products/1/images/0/thumbnail", "image/jpeg", http.StatusFound, "http://cdn/thumbnail.jpeg"},
+		{"WebP Refused Explicitly", "/api/products/1/images/0/thumbnail", "image/webp;q=0, */*", http.StatusFound, "http://cdn/thumbnail.jpeg"},
+		{"No Accept Header", "/api/products/1/images/0/thumbnail", "", http.StatusFound, "http://cdn/thumbnail.webp"},
+		{"Unknown Variant", "/api/products/1/images/0/huge", "", http.StatusNotFound, ""},
+		{"Index Out Of Range", "/api/products/1/images/3/thumbnail", "", http.StatusNotFound, ""},
+		{"Invalid Index", "/api/products/1/images/x/thumbnail", "", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.location != "" {
+				assert.Equal(t, tt.location, w.Header().Get("Location"))
+				assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			}
+		})
+	}
 }
 
 func TestGetFilteredProducts(t *testing.T) {
@@ -156,11 +320,14 @@ func TestGetFilteredProducts(t *testing.T) {
 			ProductName: "test",
 		}
 
-		expectedProducts := []models.Product{
-			{ID: 1, ProductName: "Test Product", ProductPrice: 50.0},
+		expectedPage := &services.ProductPage{
+			Items: []models.Product{
+				{ID: 1, ProductName: "Test Product", ProductPrice: 50.0},
+			},
+			Total: 1,
 		}
 
-		mockService.On("GetFilteredProducts", req).Return(expectedProducts, nil)
+		mockService.On("GetFilteredProducts", testActor, req).Return(expectedPage, nil)
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("GET", "/api/products/filter?user_id=1&min_price=10.0&max_price=100.0&product_name=test", nil)
@@ -169,10 +336,251 @@ func TestGetFilteredProducts(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, w.Code)
 
-		var response []models.Product
+		var response services.ProductPage
 		json.Unmarshal(w.Body.Bytes(), &response)
-		assert.Len(t, response, 1)
-		assert.Equal(t, expectedProducts[0].ID, response[0].ID)
+		assert.Len(t, response.Items, 1)
+		assert.Equal(t, expectedPage.Items[0].ID, response.Items[0].ID)
+		assert.Equal(t, int64(1), response.Total)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Page Parameters", func(t *testing.T) {
+		req := &services.FilterProductsRequest{
+			UserID: testActor.UserID,
+			Sort:   "-price",
+			Limit:  10,
+			Cursor: "abc",
+		}
+
+		mockService.On("GetFilteredProducts", testActor, req).Return(&services.ProductPage{}, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/filter?sort=-price&limit=10&cursor=abc", nil)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Limit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/filter?limit=ten", nil)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Defaults To Authenticated User", func(t *testing.T) {
+		req := &services.FilterProductsRequest{UserID: testActor.UserID}
+
+		mockService.On("GetFilteredProducts", testActor, req).Return(&services.ProductPage{}, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/filter", nil)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		req := &services.FilterProductsRequest{UserID: 2}
+
+		mockService.On("GetFilteredProducts", testActor, req).
+			Return((*services.ProductPage)(nil), services.ErrForbidden)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/products/filter?user_id=2", nil)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestUpdateProduct(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	t.Run("Successful Update", func(t *testing.T) {
+		req := services.UpdateProductRequest{
+			Name:   "Updated Product",
+			Price:  20,
+			Images: []string{"new.jpg"},
+		}
+
+		mockService.On("ReplaceProduct", testActor, uint(1), &req).
+			Return(&models.Product{ID: 1, ProductName: req.Name}, nil)
+
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/api/products/1", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Validation Error", func(t *testing.T) {
+		req := services.UpdateProductRequest{Price: 20}
+
+		mockService.On("ReplaceProduct", testActor, uint(2), &req).
+			Return((*models.Product)(nil), fmt.Errorf("%w: product_name is required", services.ErrInvalidProduct))
+
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/api/products/2", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPatchProduct(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	t.Run("Successful Patch", func(t *testing.T) {
+		patch := []byte(`{"product_price": 30}`)
+
+		mockService.On("PatchProduct", testActor, uint(1), patch).
+			Return(&models.Product{ID: 1, ProductPrice: 30}, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PATCH", "/api/products/1", bytes.NewBuffer(patch))
+		r.Header.Set("Content-Type", "application/merge-patch+json")
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Product Not Found", func(t *testing.T) {
+		patch := []byte(`{"product_price": 30}`)
+
+		mockService.On("PatchProduct", testActor, uint(999), patch).
+			Return((*models.Product)(nil), services.ErrProductNotFound)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PATCH", "/api/products/999", bytes.NewBuffer(patch))
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestDeleteProduct(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	mockService.On("DeleteProduct", testActor, uint(1)).Return(nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("DELETE", "/api/products/1", nil)
+
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func newMultipartUpload(t *testing.T, files map[string][]byte) (*bytes.Buffer, string) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for name, data := range files {
+		part, err := writer.CreateFormFile("images", name)
+		assert.NoError(t, err)
+		part.Write(data)
+	}
+	assert.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadProductImages(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	t.Run("Successful Upload", func(t *testing.T) {
+		data := []byte("\x89PNG\r\n\x1a\n")
+		mockService.On("AddProductImages", testActor, uint(1), []services.UploadedImage{{Filename: "a.png", Data: data}}).
+			Return(&models.Product{ID: 1}, nil)
+
+		body, contentType := newMultipartUpload(t, map[string][]byte{"a.png": data})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/1/images", body)
+		r.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("File Too Large", func(t *testing.T) {
+		body, contentType := newMultipartUpload(t, map[string][]byte{"big.png": make([]byte, 2048)})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/1/images", body)
+		r.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Too Many Files", func(t *testing.T) {
+		body, contentType := newMultipartUpload(t, map[string][]byte{"a.png": {1}, "b.png": {2}, "c.png": {3}})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/1/images", body)
+		r.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unsupported Content", func(t *testing.T) {
+		data := []byte("plain text")
+		mockService.On("AddProductImages", testActor, uint(2), []services.UploadedImage{{Filename: "a.txt", Data: data}}).
+			Return((*models.Product)(nil), services.ErrInvalidImage)
+
+		body, contentType := newMultipartUpload(t, map[string][]byte{"a.txt": data})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/2/images", body)
+		r.Header.Set("Content-Type", contentType)
+
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestReprocessProductImages(t *testing.T) {
+	router, mockService := setupTestRouter()
+
+	t.Run("Accepted", func(t *testing.T) {
+		mockService.On("ReprocessFailedImages", testActor, uint(1)).
+			Return(&models.Product{ID: 1, ProcessingStatus: models.ProcessingStatusPending}, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/1/images/reprocess", nil)
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("No Failed Images", func(t *testing.T) {
+		mockService.On("ReprocessFailedImages", testActor, uint(2)).
+			Return((*models.Product)(nil), services.ErrNoFailedImages)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/products/2/images/reprocess", nil)
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
 }
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/handlers"
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateSubscription(actor services.Actor, req *services.CreateWebhookRequest) (*services.CreatedWebhook, error) {
+	args := m.Called(actor, req)
+	return args.Get(0).(*services.CreatedWebhook), args.Error(1)
+}
+
+func (m *MockWebhookService) ListSubscriptions(actor services.Actor) ([]models.WebhookSubscription, error) {
+	args := m.Called(actor)
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookService) GetSubscription(actor services.Actor, id uint) (*models.WebhookSubscription, error) {
+	args := m.Called(actor, id)
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteSubscription(actor services.Actor, id uint) error {
+	args := m.Called(actor, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(actor services.Actor, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(actor, subscriptionID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func setupWebhookRouter() (*gin.Engine, *MockWebhookService) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", testActor.UserID)
+		c.Next()
+	})
+	mockService := new(MockWebhookService)
+	handler := handlers.NewWebhookHandler(mockService)
+
+	webhooks := router.Group("/api/webhooks")
+	{
+		webhooks.POST("/", handler.CreateWebhook)
+		webhooks.GET("/:id", handler.GetWebhook)
+		webhooks.DELETE("/:id", handler.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", handler.ListDeliveries)
+	}
+
+	return router, mockService
+}
+
+func TestCreateWebhook(t *testing.T) {
+	router, mockService := setupWebhookRouter()
+
+	t.Run("Created", func(t *testing.T) {
+		req := services.CreateWebhookRequest{URL: "https://example.com/hooks", Events: []string{models.EventProductCreated}}
+		mockService.On("CreateSubscription", testActor, &req).Return(&services.CreatedWebhook{
+			WebhookSubscription: models.WebhookSubscription{ID: 1, URL: req.URL, Secret: "secret"},
+			Secret:              "secret",
+		}, nil).Once()
+
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/webhooks/", bytes.NewReader(body))
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `"secret"`, string(mustField(t, w.Body.Bytes(), "secret")))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		req := services.CreateWebhookRequest{URL: "nope"}
+		mockService.On("CreateSubscription", testActor, &req).
+			Return((*services.CreatedWebhook)(nil), services.ErrInvalidWebhook).Once()
+
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/webhooks/", bytes.NewReader(body))
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	router, mockService := setupWebhookRouter()
+
+	mockService.On("ListDeliveries", testActor, uint(1), 50).
+		Return([]models.WebhookDelivery{{ID: 3, Status: models.WebhookDeliveryFailed}}, nil)
+	mockService.On("ListDeliveries", testActor, uint(2), 10).
+		Return([]models.WebhookDelivery(nil), services.ErrForbidden)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/webhooks/1/deliveries", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"failed"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/webhooks/2/deliveries?limit=10", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func mustField(t *testing.T, body []byte, name string) json.RawMessage {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatal(err)
+	}
+	return fields[name]
+}
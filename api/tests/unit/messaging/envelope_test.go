@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageProcessingTaskEnvelope(t *testing.T) {
+	task := messaging.ImageProcessingTask{ProductID: 7, Images: []string{"a.jpg"}}
+
+	t.Run("Round Trip", func(t *testing.T) {
+		body, err := messaging.EncodeImageProcessingTask(task, "corr-1")
+		assert.NoError(t, err)
+
+		envelope, decoded, err := messaging.DecodeImageProcessingTask(body)
+		assert.NoError(t, err)
+		assert.Equal(t, task, decoded)
+		assert.Equal(t, messaging.TypeImageProcessingTask, envelope.Type)
+		assert.Equal(t, messaging.ImageProcessingTaskVersion, envelope.Version)
+		assert.NotEmpty(t, envelope.ID)
+		assert.False(t, envelope.ProducedAt.IsZero())
+		assert.Equal(t, "corr-1", envelope.Correlation())
+	})
+
+	t.Run("Starts A Correlation Chain", func(t *testing.T) {
+		body, _ := messaging.EncodeImageProcessingTask(task, "")
+		envelope, _, err := messaging.DecodeImageProcessingTask(body)
+		assert.NoError(t, err)
+		assert.Equal(t, envelope.ID, envelope.Correlation())
+	})
+
+	t.Run("Bare Task From Older Producers", func(t *testing.T) {
+		envelope, decoded, err := messaging.DecodeImageProcessingTask([]byte(`{"product_id":7,"images":["a.jpg"]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, task, decoded)
+		assert.Equal(t, 0, envelope.Version)
+	})
+
+	t.Run("Newer Version Rejected", func(t *testing.T) {
+		envelope, _ := messaging.NewEnvelope(messaging.TypeImageProcessingTask, messaging.ImageProcessingTaskVersion+1, "", task)
+		body, _ := json.Marshal(envelope)
+
+		_, _, err := messaging.DecodeImageProcessingTask(body)
+		assert.ErrorIs(t, err, messaging.ErrUnsupportedVersion)
+	})
+
+	t.Run("Invalid Messages", func(t *testing.T) {
+		bodies := []string{
+			`not json`,
+			`{"type":"user_created","version":1,"payload":{}}`,
+			`{"type":"image_processing_task","version":1}`,
+		}
+		for _, body := range bodies {
+			_, _, err := messaging.DecodeImageProcessingTask([]byte(body))
+			assert.ErrorIs(t, err, messaging.ErrInvalidMessage, body)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, deliveries <-chan amqp.Delivery) amqp.Delivery {
+	t.Helper()
+	select {
+	case d, ok := <-deliveries:
+		require.True(t, ok, "deliveries closed")
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("no delivery")
+		return amqp.Delivery{}
+	}
+}
+
+func assertNoDelivery(t *testing.T, deliveries <-chan amqp.Delivery) {
+	t.Helper()
+	select {
+	case d, ok := <-deliveries:
+		if ok {
+			t.Fatalf("unexpected delivery %s", d.Body)
+		}
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestMemoryBrokerDelivery(t *testing.T) {
+	broker := messaging.NewMemoryBroker([]messaging.Queue{{Name: "tasks"}})
+	defer broker.Close()
+
+	assert.NoError(t, broker.Publish("tasks", []byte("1")))
+	assert.NoError(t, broker.Publish("tasks", []byte("2")))
+	assert.ErrorIs(t, broker.Publish("missing", []byte("3")), messaging.ErrUnknownQueue)
+
+	sub := broker.Subscribe("tasks", "worker", 1)
+
+	t.Run("Prefetch Limits Unacked Deliveries", func(t *testing.T) {
+		first := receive(t, sub.Deliveries())
+		assert.Equal(t, "1", string(first.Body))
+		assertNoDelivery(t, sub.Deliveries())
+
+		assert.NoError(t, first.Ack(false))
+		second := receive(t, sub.Deliveries())
+		assert.Equal(t, "2", string(second.Body))
+		assert.False(t, second.Redelivered)
+
+		t.Run("Requeued Messages Are Redelivered", func(t *testing.T) {
+			assert.NoError(t, second.Nack(false, true))
+			again := receive(t, sub.Deliveries())
+			assert.Equal(t, "2", string(again.Body))
+			assert.True(t, again.Redelivered)
+			assert.NoError(t, again.Ack(false))
+			assert.Error(t, again.Ack(false))
+		})
+	})
+
+	t.Run("Cancel Closes Deliveries", func(t *testing.T) {
+		assert.NoError(t, sub.Cancel())
+		_, ok := <-sub.Deliveries()
+		assert.False(t, ok)
+
+		assert.NoError(t, broker.Publish("tasks", []byte("4")))
+		assert.Len(t, broker.Messages("tasks"), 1)
+	})
+}
+
+func TestMemoryBrokerDeadLetters(t *testing.T) {
+	broker := messaging.NewMemoryBroker([]messaging.Queue{
+		{Name: "tasks", Args: amqp.Table{"x-dead-letter-exchange": "", "x-dead-letter-routing-key": "tasks_dlq"}},
+		{Name: "tasks_dlq"},
+		messaging.RetryQueue("tasks", 10*time.Millisecond),
+	})
+	defer broker.Close()
+
+	t.Run("Rejected Messages Are Dead Lettered", func(t *testing.T) {
+		sub := broker.Subscribe("tasks", "worker", 1)
+		assert.NoError(t, broker.PublishMessage(context.Background(), "tasks", amqp.Publishing{MessageId: "a", Body: []byte("a")}))
+
+		d := receive(t, sub.Deliveries())
+		assert.NoError(t, d.Nack(false, false))
+		assert.NoError(t, sub.Cancel())
+
+		dead := broker.Messages("tasks_dlq")
+		if assert.Len(t, dead, 1) {
+			assert.Equal(t, "a", dead[0].MessageId)
+		}
+	})
+
+	t.Run("Expired Messages Return To Their Queue", func(t *testing.T) {
+		retryQueue := messaging.RetryQueueName("tasks", 10*time.Millisecond)
+		assert.NoError(t, broker.Publish(retryQueue, []byte("b")))
+		assert.Len(t, broker.Messages(retryQueue), 1)
+
+		sub := broker.Subscribe("tasks", "worker", 1)
+		defer sub.Cancel()
+		d := receive(t, sub.Deliveries())
+		assert.Equal(t, "b", string(d.Body))
+		assert.Empty(t, broker.Messages(retryQueue))
+	})
+}
+
+func TestMemoryDeadLetterQueue(t *testing.T) {
+	broker := messaging.NewMemoryBroker([]messaging.Queue{{Name: "tasks"}, {Name: "tasks_dlq"}})
+	defer broker.Close()
+	dlq := broker.DeadLetterQueue("tasks_dlq", "tasks")
+
+	products := map[string]uint{"a": 1, "b": 2, "c": 2}
+	for _, id := range []string{"a", "b", "c"} {
+		body, err := messaging.EncodeImageProcessingTask(messaging.ImageProcessingTask{
+			ProductID: products[id],
+			Images:    []string{"a.jpg"},
+		}, "")
+		require.NoError(t, err)
+		require.NoError(t, broker.PublishMessage(context.Background(), "tasks_dlq", amqp.Publishing{
+			MessageId: id,
+			Headers:   amqp.Table{messaging.HeaderAttempts: int32(3)},
+			Body:      body,
+		}))
+	}
+
+	listed, err := dlq.List(context.Background(), messaging.DeadLetterFilter{}, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, "a", listed[0].ID)
+		assert.Equal(t, 3, listed[0].Attempts)
+	}
+
+	replayed, err := dlq.Replay(context.Background(), messaging.DeadLetterFilter{ProductID: 2})
+	assert.NoError(t, err)
+	assert.Len(t, replayed, 2)
+	tasks := broker.Messages("tasks")
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "b", tasks[0].MessageId)
+		// Replays start over with a clean attempt count
+		assert.Empty(t, tasks[0].Headers)
+	}
+
+	purged, err := dlq.Purge(context.Background(), messaging.DeadLetterFilter{IDs: []string{"a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, broker.Messages("tasks_dlq"))
+}
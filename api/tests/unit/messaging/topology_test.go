@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/image-processor/queue"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageProcessingTopology(t *testing.T) {
+	topology := messaging.ImageProcessingTopology([]time.Duration{5 * time.Second, 2 * time.Minute})
+
+	names := make([]string, len(topology))
+	for i, q := range topology {
+		names[i] = q.Name
+	}
+	assert.Equal(t, []string{
+		"image_processing",
+		"image_processing_dlq",
+		"image_processing.retry.5000ms",
+		"image_processing.retry.120000ms",
+	}, names)
+
+	// Delay queues dead-letter back onto the task queue once the TTL expires
+	retry := topology[2].Args
+	assert.Equal(t, int64(5000), retry["x-message-ttl"])
+	assert.Equal(t, "", retry["x-dead-letter-exchange"])
+	assert.Equal(t, "image_processing", retry["x-dead-letter-routing-key"])
+
+	t.Run("Consumer Declares Default Delays", func(t *testing.T) {
+		assert.Len(t, queue.Topology(queue.RetryPolicy{}), 2+4)
+	})
+}
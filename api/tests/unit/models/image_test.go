@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeImageResults(t *testing.T) {
+	variants := []models.ImageVariant{{Name: "thumbnail", URL: "thumb.jpg"}}
+
+	images := models.ImageVariants{
+		{Source: "a.jpg", Status: models.ImageStatusDone, Attempts: 1, Variants: variants},
+		{Source: "b.jpg", Status: models.ImageStatusProcessing},
+		{Source: "removed.jpg", Status: models.ImageStatusFailed},
+	}
+
+	merged := models.MergeImageResults([]string{"a.jpg", "b.jpg", "c.jpg"}, images, []models.ImageResult{
+		{Source: "b.jpg", Status: models.ImageStatusFailed, Error: "decode failed", Attempted: true},
+		{Source: "removed.jpg", Status: models.ImageStatusDone, Attempted: true},
+	})
+
+	assert.Equal(t, models.ImageVariants{
+		{Source: "a.jpg", Status: models.ImageStatusDone, Attempts: 1, Variants: variants},
+		{Source: "b.jpg", Status: models.ImageStatusFailed, Error: "decode failed", Attempts: 1},
+		{Source: "c.jpg", Status: models.ImageStatusPending},
+	}, merged)
+
+	t.Run("Success Clears Error", func(t *testing.T) {
+		merged = models.MergeImageResults([]string{"b.jpg"}, merged, []models.ImageResult{
+			{Source: "b.jpg", Status: models.ImageStatusDone, Variants: variants, Attempted: true},
+		})
+
+		assert.Equal(t, models.ImageVariants{
+			{Source: "b.jpg", Status: models.ImageStatusDone, Attempts: 2, Variants: variants},
+		}, merged)
+	})
+
+	t.Run("Status Change Keeps Attempts", func(t *testing.T) {
+		merged = models.MergeImageResults([]string{"b.jpg"}, merged, []models.ImageResult{
+			{Source: "b.jpg", Status: models.ImageStatusProcessing},
+		})
+
+		assert.Equal(t, 2, merged[0].Attempts)
+		assert.Equal(t, variants, merged[0].Variants)
+	})
+}
+
+func TestProcessingStatusOf(t *testing.T) {
+	image := func(status string) models.ProcessedImage {
+		return models.ProcessedImage{Status: status}
+	}
+
+	tests := []struct {
+		name   string
+		images models.ImageVariants
+		want   string
+	}{
+		{"No Images", nil, models.ProcessingStatusCompleted},
+		{"All Done", models.ImageVariants{image(models.ImageStatusDone), image(models.ImageStatusDone)}, models.ProcessingStatusCompleted},
+		{"All Failed", models.ImageVariants{image(models.ImageStatusFailed)}, models.ProcessingStatusFailed},
+		{"Some Failed", models.ImageVariants{image(models.ImageStatusDone), image(models.ImageStatusFailed)}, models.ProcessingStatusPartiallyCompleted},
+		{"Retry Pending", models.ImageVariants{image(models.ImageStatusDone), image(models.ImageStatusPending)}, models.ProcessingStatusPending},
+		{"Processing Wins", models.ImageVariants{image(models.ImageStatusPending), image(models.ImageStatusProcessing)}, models.ProcessingStatusProcessing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, models.ProcessingStatusOf(tt.images))
+		})
+	}
+}
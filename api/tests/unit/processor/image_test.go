@@ -0,0 +1,176 @@
+// api/tests/unit/processor/image_test.go
+package tests
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/image-processor/processor"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVariantProfiles(t *testing.T) {
+	t.Run("Valid Profiles", func(t *testing.T) {
+		profiles, err := processor.ParseVariantProfiles("thumbnail:150x150:crop:70, wide:800x0:fit:85")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []processor.VariantProfile{
+			{Name: "thumbnail", MaxWidth: 150, MaxHeight: 150, Mode: processor.CropMode, Quality: 70},
+			{Name: "wide", MaxWidth: 800, MaxHeight: 0, Mode: processor.FitMode, Quality: 85},
+		}, profiles)
+	})
+
+	for name, spec := range map[string]string{
+		"Empty":            "",
+		"Missing Parts":    "thumbnail:150x150",
+		"Bad Size":         "thumbnail:150:crop:70",
+		"Unknown Mode":     "thumbnail:150x150:stretch:70",
+		"Bad Quality":      "thumbnail:150x150:crop:101",
+		"Unbounded Crop":   "thumbnail:150x0:crop:70",
+		"Duplicate Name":   "a:10x10:fit:70,a:20x20:fit:70",
+		"Negative Width":   "a:-10x10:fit:70",
+		"Non Numeric Size": "a:axb:fit:70",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := processor.ParseVariantProfiles(spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseOutputFormats(t *testing.T) {
+	formats, err := processor.ParseOutputFormats("original, WebP,original")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{processor.OriginalFormat, "webp"}, formats)
+
+	_, err = processor.ParseOutputFormats("gif")
+	assert.Error(t, err)
+
+	_, err = processor.ParseOutputFormats("")
+	assert.Error(t, err)
+}
+
+func TestVariantResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	t.Run("Fit Keeps Aspect Ratio", func(t *testing.T) {
+		out := processor.VariantProfile{MaxWidth: 100, MaxHeight: 100, Mode: processor.FitMode}.Resize(src)
+		assert.Equal(t, image.Rect(0, 0, 100, 50), out.Bounds())
+	})
+
+	t.Run("Fit Never Upscales", func(t *testing.T) {
+		out := processor.VariantProfile{MaxWidth: 1000, MaxHeight: 1000, Mode: processor.FitMode}.Resize(src)
+		assert.Equal(t, src.Bounds(), out.Bounds())
+	})
+
+	t.Run("Crop Fills Box", func(t *testing.T) {
+		out := processor.VariantProfile{MaxWidth: 100, MaxHeight: 100, Mode: processor.CropMode}.Resize(src)
+		assert.Equal(t, image.Rect(0, 0, 100, 100), out.Bounds())
+	})
+
+	t.Run("Crop Shrinks Box For Small Source", func(t *testing.T) {
+		out := processor.VariantProfile{MaxWidth: 300, MaxHeight: 300, Mode: processor.CropMode}.Resize(src)
+		assert.Equal(t, image.Rect(0, 0, 300, 200), out.Bounds())
+	})
+}
+
+func TestProcessImage(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(storage.LocalConfig{Root: t.TempDir(), PublicURL: "http://localhost/files"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 480))))
+	require.NoError(t, store.Put(ctx, "products/1/originals/a.png", buf.Bytes(), "image/png"))
+
+	profiles, err := processor.ParseVariantProfiles("thumbnail:100x100:crop:70,medium:320x320:fit:80")
+	require.NoError(t, err)
+	imageProcessor := processor.NewImageProcessor(store, processor.Config{Variants: profiles, Formats: []string{processor.OriginalFormat, "webp"}})
+
+	result, err := imageProcessor.ProcessImage(ctx, store.URL("products/1/originals/a.png"))
+
+	require.NoError(t, err)
+	assert.Equal(t, store.URL("products/1/originals/a.png"), result.Source)
+	require.Len(t, result.Variants, 2)
+
+	thumbnail, medium := result.Variants[0], result.Variants[1]
+	assert.Equal(t, "thumbnail", thumbnail.Name)
+	assert.Equal(t, 100, thumbnail.Width)
+	assert.Equal(t, 100, thumbnail.Height)
+	assert.Equal(t, "medium", medium.Name)
+	assert.Equal(t, 320, medium.Width)
+	assert.Equal(t, 240, medium.Height)
+	assert.Equal(t, "png", medium.Format)
+
+	// The original format is primary and every format is listed
+	require.Len(t, medium.Formats, 2)
+	assert.Equal(t, "png", medium.Formats[0].Format)
+	assert.Equal(t, medium.URL, medium.Formats[0].URL)
+	assert.Equal(t, "webp", medium.Formats[1].Format)
+
+	// Every format is written to the store under its URL
+	for _, file := range medium.Formats {
+		key, ok := storage.KeyFromURL(store, file.URL)
+		require.True(t, ok)
+		info, err := store.Stat(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, file.Size, info.Size)
+
+		body, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		decoded, format, err := image.Decode(body)
+		body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, file.Format, format)
+		assert.Equal(t, image.Rect(0, 0, 320, 240), decoded.Bounds())
+	}
+}
+
+func TestProcessImageLimits(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(storage.LocalConfig{Root: t.TempDir(), PublicURL: "http://localhost/files"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 100))))
+	require.NoError(t, store.Put(ctx, "a.png", buf.Bytes(), "image/png"))
+
+	profiles, err := processor.ParseVariantProfiles("thumbnail:100x100:crop:70")
+	require.NoError(t, err)
+
+	t.Run("File Too Large", func(t *testing.T) {
+		imageProcessor := processor.NewImageProcessor(store, processor.Config{
+			Variants:         profiles,
+			Formats:          []string{processor.OriginalFormat},
+			MaxDownloadBytes: int64(buf.Len() - 1),
+		})
+		_, err := imageProcessor.ProcessImage(ctx, store.URL("a.png"))
+		assert.ErrorContains(t, err, "exceeds")
+	})
+
+	t.Run("Too Many Pixels", func(t *testing.T) {
+		imageProcessor := processor.NewImageProcessor(store, processor.Config{
+			Variants:  profiles,
+			Formats:   []string{processor.OriginalFormat},
+			MaxPixels: 200*100 - 1,
+		})
+		_, err := imageProcessor.ProcessImage(ctx, store.URL("a.png"))
+		assert.ErrorContains(t, err, "too large")
+	})
+
+	t.Run("Cancelled Context", func(t *testing.T) {
+		imageProcessor := processor.NewImageProcessor(store, processor.Config{
+			Variants: profiles,
+			Formats:  []string{processor.OriginalFormat},
+		})
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := imageProcessor.ProcessImage(cancelled, store.URL("a.png"))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,358 @@
+// api/tests/unit/queue/consumer_test.go
+package tests
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/image-processor/processor"
+	"github.com/KPVISHNUSAI/product-management-system/image-processor/queue"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// productStore keeps products and their outbox in memory. It serves both
+// the API's product and outbox repositories and the consumer's.
+type productStore struct {
+	services.ProductRepository
+
+	mu       sync.Mutex
+	products map[uint]*models.Product
+	outbox   []models.OutboxMessage
+	// merged receives each product after image results were merged into it
+	merged chan models.Product
+}
+
+func newProductStore() *productStore {
+	return &productStore{
+		products: make(map[uint]*models.Product),
+		merged:   make(chan models.Product, 16),
+	}
+}
+
+func (s *productStore) CreateWithOutbox(product *models.Product, outbox func(*models.Product) ([]models.OutboxMessage, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product.ID = uint(len(s.products) + 1)
+	messages, err := outbox(product)
+	if err != nil {
+		return err
+	}
+	stored := *product
+	s.products[product.ID] = &stored
+	for _, message := range messages {
+		message.ID = uint(len(s.outbox) + 1)
+		s.outbox = append(s.outbox, message)
+	}
+	return nil
+}
+
+func (s *productStore) MergeImageResults(id uint, results []models.ImageResult) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, ok := s.products[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	product.ImageVariants = models.MergeImageResults(product.ProductImages, product.ImageVariants, results)
+	product.ProcessingStatus = models.ProcessingStatusOf(product.ImageVariants)
+
+	merged := *product
+	s.merged <- merged
+	return &merged, nil
+}
+
+func (s *productStore) ClaimPending(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []models.OutboxMessage
+	for _, message := range s.outbox {
+		if message.SentAt == nil && len(pending) < limit {
+			pending = append(pending, message)
+		}
+	}
+	return pending, nil
+}
+
+func (s *productStore) MarkSent(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.outbox[id-1].SentAt = &now
+	return nil
+}
+
+func (s *productStore) MarkFailed(id uint, attempts int, lastError string, nextAttemptAt time.Time) error {
+	return nil
+}
+
+func (s *productStore) DeleteSentBefore(t time.Time) (int64, error) {
+	return 0, nil
+}
+
+// deletedKeysCache is an empty cache that records the keys deleted from it.
+type deletedKeysCache struct {
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (c *deletedKeysCache) Get(ctx context.Context, key string, dest interface{}) error {
+	return cache.ErrMiss
+}
+
+func (c *deletedKeysCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return nil
+}
+
+func (c *deletedKeysCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted = append(c.deleted, key)
+	return nil
+}
+
+func (c *deletedKeysCache) BatchGetInto(ctx context.Context, dests map[string]interface{}) ([]string, error) {
+	missing := make([]string, 0, len(dests))
+	for key := range dests {
+		missing = append(missing, key)
+	}
+	return missing, nil
+}
+
+func (c *deletedKeysCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	return nil
+}
+
+func (c *deletedKeysCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return nil
+}
+
+func (c *deletedKeysCache) Deleted() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.deleted...)
+}
+
+// resizer pretends to process an image into a single variant.
+type resizer struct{}
+
+func (resizer) ProcessImage(ctx context.Context, imageURL string) (models.ProcessedImage, error) {
+	return models.ProcessedImage{
+		Source:   imageURL,
+		Variants: []models.ImageVariant{{Name: "thumbnail", URL: imageURL + "?w=150"}},
+	}, nil
+}
+
+// flakyResizer fails the images it has an error for and counts how often
+// each image was processed.
+type flakyResizer struct {
+	errs map[string]error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *flakyResizer) ProcessImage(ctx context.Context, imageURL string) (models.ProcessedImage, error) {
+	r.mu.Lock()
+	r.calls[imageURL]++
+	r.mu.Unlock()
+
+	if err := r.errs[imageURL]; err != nil {
+		return models.ProcessedImage{}, err
+	}
+	return resizer{}.ProcessImage(ctx, imageURL)
+}
+
+func (r *flakyResizer) Calls(imageURL string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[imageURL]
+}
+
+// refusingBroker is a memory broker that never confirms publishes to one
+// queue.
+type refusingBroker struct {
+	*messaging.MemoryBroker
+	queue   string
+	refused atomic.Int32
+}
+
+func (b *refusingBroker) PublishMessage(ctx context.Context, queue string, msg amqp.Publishing) error {
+	if queue == b.queue {
+		b.refused.Add(1)
+		return errors.New("publish not confirmed")
+	}
+	return b.MemoryBroker.PublishMessage(ctx, queue, msg)
+}
+
+type nopStatusPublisher struct{}
+
+func (nopStatusPublisher) PublishProcessingStatus(product *models.Product) error {
+	return nil
+}
+
+var testRetry = queue.RetryPolicy{Delays: []time.Duration{time.Second}}
+
+// startConsumer runs a consumer of the image processing queue on a memory
+// broker until the test ends.
+func startConsumer(t *testing.T, store *productStore, productService queue.ProductService) *messaging.MemoryBroker {
+	broker := messaging.NewMemoryBroker(queue.Topology(testRetry))
+	t.Cleanup(func() { broker.Close() })
+
+	runConsumer(t, broker, resizer{}, store, productService, testRetry)
+	return broker
+}
+
+func runConsumer(t *testing.T, broker queue.Broker, images queue.ImageProcessor, store *productStore, productService queue.ProductService, retry queue.RetryPolicy) {
+	consumer, err := queue.NewConsumer(broker, images, store, productService, nopStatusPublisher{}, queue.Config{
+		Workers: 1,
+		Retry:   retry,
+	})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+	t.Cleanup(func() { consumer.Shutdown(context.Background()) })
+}
+
+func droppedTasks() int64 {
+	dropped, _ := expvar.Get("image_processor").(*expvar.Map).Get("tasks_dropped").(*expvar.Int)
+	if dropped == nil {
+		return 0
+	}
+	return dropped.Value()
+}
+
+func TestConsumerProcessesTasksQueuedByTheAPI(t *testing.T) {
+	store := newProductStore()
+	productCache := &deletedKeysCache{}
+	productService := services.NewProductService(store, productCache, nil, nil, services.ProductCacheConfig{})
+	broker := startConsumer(t, store, productService)
+	relay := services.NewOutboxRelay(store, broker, services.OutboxConfig{})
+
+	product, err := productService.CreateProduct(services.Actor{UserID: 1}, &services.CreateProductRequest{
+		Name:   "Lamp",
+		Price:  10,
+		Images: []string{"http://images.example.com/lamp.jpg"},
+	})
+	require.NoError(t, err)
+
+	relayed, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, relayed)
+
+	var processed models.Product
+	for processed.ProcessingStatus != models.ProcessingStatusCompleted {
+		select {
+		case processed = <-store.merged:
+		case <-time.After(time.Second):
+			t.Fatalf("product not processed, last status %q", processed.ProcessingStatus)
+		}
+	}
+
+	assert.Equal(t, product.ID, processed.ID)
+	require.Len(t, processed.ImageVariants, 1)
+	assert.Equal(t, models.ImageStatusDone, processed.ImageVariants[0].Status)
+	assert.Equal(t, "http://images.example.com/lamp.jpg?w=150", processed.ImageVariants[0].Variants[0].URL)
+	assert.Contains(t, productCache.Deleted(), "product:1")
+	assert.Empty(t, broker.Messages(messaging.ImageProcessingDLQ))
+}
+
+func TestConsumerDropsTasksOfDeletedProducts(t *testing.T) {
+	store := newProductStore()
+	productService := services.NewProductService(store, &deletedKeysCache{}, nil, nil, services.ProductCacheConfig{})
+	broker := startConsumer(t, store, productService)
+	before := droppedTasks()
+
+	body, err := messaging.EncodeImageProcessingTask(messaging.ImageProcessingTask{
+		ProductID: 42,
+		Images:    []string{"http://images.example.com/gone.jpg"},
+	}, "")
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(messaging.ImageProcessingQueue, body))
+
+	assert.Eventually(t, func() bool { return droppedTasks() == before+1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, broker.Messages(messaging.ImageProcessingQueue))
+	assert.Empty(t, broker.Messages(messaging.RetryQueueName(messaging.ImageProcessingQueue, time.Second)))
+	assert.Empty(t, broker.Messages(messaging.ImageProcessingDLQ))
+}
+
+func TestConsumerKeepsRetriesWhenDeadLetteringFails(t *testing.T) {
+	// Retries wait long enough not to come back during the test
+	retry := queue.RetryPolicy{Delays: []time.Duration{time.Minute}}
+	broker := &refusingBroker{
+		MemoryBroker: messaging.NewMemoryBroker(queue.Topology(retry)),
+		queue:        messaging.ImageProcessingDLQ,
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	images := &flakyResizer{
+		errs: map[string]error{
+			"http://images.example.com/flaky.jpg":  errors.New("connection reset"),
+			"http://images.example.com/broken.jpg": processor.ErrInvalidImage,
+		},
+		calls: make(map[string]int),
+	}
+	store := newProductStore()
+	productService := services.NewProductService(store, &deletedKeysCache{}, nil, nil, services.ProductCacheConfig{})
+	runConsumer(t, broker, images, store, productService, retry)
+
+	sources := []string{
+		"http://images.example.com/ok.jpg",
+		"http://images.example.com/flaky.jpg",
+		"http://images.example.com/broken.jpg",
+	}
+	product := &models.Product{UserID: 1, ProductName: "Lamp", ProductImages: sources}
+	require.NoError(t, store.CreateWithOutbox(product, func(*models.Product) ([]models.OutboxMessage, error) {
+		return nil, nil
+	}))
+
+	body, err := messaging.EncodeImageProcessingTask(messaging.ImageProcessingTask{
+		ProductID: product.ID,
+		Images:    sources,
+	}, "")
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(messaging.ImageProcessingQueue, body))
+
+	retryQueue := messaging.RetryQueueName(messaging.ImageProcessingQueue, time.Minute)
+	require.Eventually(t, func() bool { return len(broker.Messages(retryQueue)) == 1 }, time.Second, 5*time.Millisecond)
+	// Every attempt at dead-lettering was refused, and the task was settled
+	// without being redelivered
+	require.Eventually(t, func() bool { return broker.refused.Load() == 3 }, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Empty(t, broker.Messages(messaging.ImageProcessingQueue))
+	assert.Empty(t, broker.Messages(messaging.ImageProcessingDLQ))
+	for _, source := range sources {
+		assert.Equal(t, 1, images.Calls(source), source)
+	}
+
+	_, retried, err := messaging.DecodeImageProcessingTask(broker.Messages(retryQueue)[0].Body)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://images.example.com/flaky.jpg"}, retried.Images)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	statuses := make(map[string]string)
+	for _, image := range store.products[product.ID].ImageVariants {
+		statuses[image.Source] = image.Status
+	}
+	assert.Equal(t, map[string]string{
+		"http://images.example.com/ok.jpg":     models.ImageStatusDone,
+		"http://images.example.com/flaky.jpg":  models.ImageStatusPending,
+		"http://images.example.com/broken.jpg": models.ImageStatusFailed,
+	}, statuses)
+}
@@ -0,0 +1,64 @@
+// api/tests/unit/queue/retry_test.go
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/image-processor/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryDelays(t *testing.T) {
+	delays, err := queue.ParseRetryDelays("5s, 30s,2m")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}, delays)
+
+	_, err = queue.ParseRetryDelays("5s,soon")
+	assert.Error(t, err)
+
+	_, err = queue.ParseRetryDelays("-5s")
+	assert.Error(t, err)
+}
+
+func TestRetryPolicyNextDelay(t *testing.T) {
+	t.Run("Backs Off Then Gives Up", func(t *testing.T) {
+		policy := queue.RetryPolicy{Delays: []time.Duration{time.Second, time.Minute}, MaxAttempts: 4}
+
+		tests := []struct {
+			failures int
+			delay    time.Duration
+			ok       bool
+		}{
+			// A delivery without failures recorded gets the first delay
+			{0, time.Second, true},
+			{1, time.Second, true},
+			{2, time.Minute, true},
+			// Past the configured delays the last one repeats
+			{3, time.Minute, true},
+			{4, 0, false},
+		}
+		for _, tt := range tests {
+			delay, ok := policy.NextDelay(tt.failures)
+			assert.Equal(t, tt.delay, delay, "failures %d", tt.failures)
+			assert.Equal(t, tt.ok, ok, "failures %d", tt.failures)
+		}
+	})
+
+	t.Run("Defaults Allow One Attempt Per Delay", func(t *testing.T) {
+		policy := queue.RetryPolicy{Delays: []time.Duration{time.Second, time.Minute}}
+
+		_, ok := policy.NextDelay(2)
+		assert.True(t, ok)
+		_, ok = policy.NextDelay(3)
+		assert.False(t, ok)
+	})
+
+	t.Run("Falls Back To The Default Delays", func(t *testing.T) {
+		policy := queue.RetryPolicy{MaxAttempts: 3}
+
+		delay, ok := policy.NextDelay(0)
+		assert.True(t, ok)
+		assert.Equal(t, 5*time.Second, delay)
+	})
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockDeadLetterQueue struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterQueue) List(ctx context.Context, filter messaging.DeadLetterFilter, limit int) ([]messaging.DeadLetter, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]messaging.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) Purge(ctx context.Context, filter messaging.DeadLetterFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) Replay(ctx context.Context, filter messaging.DeadLetterFilter) ([]messaging.DeadLetter, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]messaging.DeadLetter), args.Error(1)
+}
+
+type MockCacheInvalidator struct {
+	mock.Mock
+}
+
+func (m *MockCacheInvalidator) InvalidateCache(product *models.Product) error {
+	args := m.Called(product)
+	return args.Error(0)
+}
+
+func TestGetDeadLetter(t *testing.T) {
+	mockQueue := new(MockDeadLetterQueue)
+	service := services.NewDeadLetterService(mockQueue, new(MockProductRepo), new(MockCacheInvalidator))
+
+	mockQueue.On("List", mock.Anything, messaging.DeadLetterFilter{IDs: []string{"a"}}, 1).
+		Return([]messaging.DeadLetter{{ID: "a", ProductID: 1}}, nil)
+	mockQueue.On("List", mock.Anything, messaging.DeadLetterFilter{IDs: []string{"missing"}}, 1).
+		Return([]messaging.DeadLetter{}, nil)
+
+	message, err := service.GetDeadLetter("a")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), message.ProductID)
+
+	_, err = service.GetDeadLetter("missing")
+	assert.ErrorIs(t, err, services.ErrDeadLetterNotFound)
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	t.Run("Resets Replayed Images Once Per Product", func(t *testing.T) {
+		mockQueue := new(MockDeadLetterQueue)
+		mockRepo := new(MockProductRepo)
+		mockCache := new(MockCacheInvalidator)
+		service := services.NewDeadLetterService(mockQueue, mockRepo, mockCache)
+
+		filter := messaging.DeadLetterFilter{ErrorClass: messaging.ErrorClassTimeout}
+		mockQueue.On("Replay", mock.Anything, filter).Return([]messaging.DeadLetter{
+			{ID: "a", ProductID: 1, Images: []string{"a.jpg"}},
+			{ID: "b", ProductID: 1, Images: []string{"b.jpg"}},
+			{ID: "c", ProductID: 2, Images: []string{"c.jpg"}},
+		}, nil)
+		mockRepo.On("MergeImageResults", uint(1), []models.ImageResult{
+			{Source: "a.jpg", Status: models.ImageStatusPending},
+			{Source: "b.jpg", Status: models.ImageStatusPending},
+		}).Return(&models.Product{ID: 1}, nil).Once()
+		mockRepo.On("MergeImageResults", uint(2), []models.ImageResult{
+			{Source: "c.jpg", Status: models.ImageStatusPending},
+		}).Return(&models.Product{ID: 2}, nil).Once()
+		mockCache.On("InvalidateCache", &models.Product{ID: 1}).Return(nil).Once()
+		mockCache.On("InvalidateCache", &models.Product{ID: 2}).Return(nil).Once()
+
+		replayed, err := service.ReplayDeadLetters(services.DeadLetterFilter{ErrorClass: messaging.ErrorClassTimeout})
+
+		assert.NoError(t, err)
+		assert.Len(t, replayed, 3)
+		mockRepo.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("Resets Products Replayed Before A Failure", func(t *testing.T) {
+		mockQueue := new(MockDeadLetterQueue)
+		mockRepo := new(MockProductRepo)
+		mockCache := new(MockCacheInvalidator)
+		service := services.NewDeadLetterService(mockQueue, mockRepo, mockCache)
+
+		mockQueue.On("Replay", mock.Anything, messaging.DeadLetterFilter{ProductID: 3}).
+			Return([]messaging.DeadLetter{{ID: "a", ProductID: 3, Images: []string{"a.jpg"}}}, errors.New("connection closed"))
+		mockRepo.On("MergeImageResults", uint(3), []models.ImageResult{
+			{Source: "a.jpg", Status: models.ImageStatusPending},
+		}).Return(&models.Product{ID: 3}, nil)
+		mockCache.On("InvalidateCache", &models.Product{ID: 3}).Return(nil)
+
+		replayed, err := service.ReplayDeadLetters(services.DeadLetterFilter{ProductID: 3})
+
+		assert.Error(t, err)
+		assert.Len(t, replayed, 1)
+		mockRepo.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("Keeps The Cache When The Reset Fails", func(t *testing.T) {
+		mockQueue := new(MockDeadLetterQueue)
+		mockRepo := new(MockProductRepo)
+		mockCache := new(MockCacheInvalidator)
+		service := services.NewDeadLetterService(mockQueue, mockRepo, mockCache)
+
+		mockQueue.On("Replay", mock.Anything, messaging.DeadLetterFilter{ProductID: 4}).
+			Return([]messaging.DeadLetter{{ID: "a", ProductID: 4, Images: []string{"a.jpg"}}}, nil)
+		mockRepo.On("MergeImageResults", uint(4), mock.Anything).Return((*models.Product)(nil), gorm.ErrRecordNotFound)
+
+		_, err := service.ReplayDeadLetters(services.DeadLetterFilter{ProductID: 4})
+
+		assert.NoError(t, err)
+		mockCache.AssertNotCalled(t, "InvalidateCache", mock.Anything)
+	})
+
+	t.Run("Empty Filter Requires All", func(t *testing.T) {
+		mockQueue := new(MockDeadLetterQueue)
+		service := services.NewDeadLetterService(mockQueue, new(MockProductRepo), new(MockCacheInvalidator))
+
+		_, err := service.ReplayDeadLetters(services.DeadLetterFilter{})
+		assert.ErrorIs(t, err, services.ErrInvalidFilter)
+
+		mockQueue.On("Replay", mock.Anything, messaging.DeadLetterFilter{}).Return([]messaging.DeadLetter{}, nil)
+		_, err = service.ReplayDeadLetters(services.DeadLetterFilter{All: true})
+		assert.NoError(t, err)
+	})
+}
+
+func TestPurgeDeadLetters(t *testing.T) {
+	mockQueue := new(MockDeadLetterQueue)
+	service := services.NewDeadLetterService(mockQueue, new(MockProductRepo), new(MockCacheInvalidator))
+
+	_, err := service.PurgeDeadLetters(services.DeadLetterFilter{})
+	assert.ErrorIs(t, err, services.ErrInvalidFilter)
+	mockQueue.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+
+	mockQueue.On("Purge", mock.Anything, messaging.DeadLetterFilter{IDs: []string{"a", "b"}}).Return(2, nil)
+	purged, err := service.PurgeDeadLetters(services.DeadLetterFilter{IDs: []string{"a", "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+}
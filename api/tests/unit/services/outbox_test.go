@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutboxRepo struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepo) ClaimPending(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]models.OutboxMessage), args.Error(1)
+}
+
+func (m *MockOutboxRepo) MarkSent(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepo) MarkFailed(id uint, attempts int, lastError string, nextAttemptAt time.Time) error {
+	args := m.Called(id, attempts, lastError, nextAttemptAt)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepo) DeleteSentBefore(t time.Time) (int64, error) {
+	args := m.Called(t)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestRelayOutbox(t *testing.T) {
+	mockRepo := new(MockOutboxRepo)
+	mockPublisher := new(MockPublisher)
+	relay := services.NewOutboxRelay(mockRepo, mockPublisher, services.OutboxConfig{
+		BatchSize: 10,
+		Backoff:   time.Minute,
+	})
+
+	mockRepo.On("ClaimPending", 10, mock.Anything).Return([]models.OutboxMessage{
+		{ID: 1, Queue: "image_processing", Payload: []byte(`{"product_id":1}`)},
+		{ID: 2, Queue: "image_processing", Payload: []byte(`{"product_id":2}`), Attempts: 2},
+	}, nil)
+	mockPublisher.On("Publish", "image_processing", []byte(`{"product_id":1}`)).Return(nil)
+	mockPublisher.On("Publish", "image_processing", []byte(`{"product_id":2}`)).Return(errors.New("connection refused"))
+	mockRepo.On("MarkSent", uint(1)).Return(nil)
+	mockRepo.On("MarkFailed", uint(2), 3, "connection refused", mock.AnythingOfType("time.Time")).Return(nil)
+
+	n, err := relay.RelayPending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "MarkSent", uint(2))
+
+	// The third attempt waits four times the initial backoff
+	retryAt := mockRepo.Calls[2].Arguments.Get(3).(time.Time)
+	assert.WithinDuration(t, time.Now().Add(4*time.Minute), retryAt, 5*time.Second)
+}
+
+func TestCleanupOutbox(t *testing.T) {
+	mockRepo := new(MockOutboxRepo)
+	relay := services.NewOutboxRelay(mockRepo, new(MockPublisher), services.OutboxConfig{Retention: 24 * time.Hour})
+
+	mockRepo.On("DeleteSentBefore", mock.MatchedBy(func(before time.Time) bool {
+		return time.Until(before) < -23*time.Hour && time.Until(before) > -25*time.Hour
+	})).Return(int64(3), nil)
+
+	n, err := relay.Cleanup()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	mockRepo.AssertExpectations(t)
+}
@@ -3,14 +3,19 @@ package tests
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // Mock implementations
@@ -23,19 +28,28 @@ func (m *MockProductRepo) Create(product *models.Product) error {
 	return args.Error(0)
 }
 
+func (m *MockProductRepo) CreateWithOutbox(product *models.Product, outbox func(*models.Product) ([]models.OutboxMessage, error)) error {
+	messages, err := outbox(product)
+	if err != nil {
+		return err
+	}
+	args := m.Called(product, messages)
+	return args.Error(0)
+}
+
 func (m *MockProductRepo) GetByID(id uint) (*models.Product, error) {
 	args := m.Called(id)
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) GetByUserID(userID uint) ([]models.Product, error) {
-	args := m.Called(userID)
+func (m *MockProductRepo) GetByIDs(ids []uint) ([]models.Product, error) {
+	args := m.Called(ids)
 	return args.Get(0).([]models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) GetFilteredProducts(userID uint, minPrice, maxPrice float64, productName string) ([]models.Product, error) {
-	args := m.Called(userID, minPrice, maxPrice, productName)
-	return args.Get(0).([]models.Product), args.Error(1)
+func (m *MockProductRepo) GetFilteredProducts(filter models.ProductFilter) ([]models.Product, int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockProductRepo) Update(product *models.Product) error {
@@ -43,14 +57,29 @@ func (m *MockProductRepo) Update(product *models.Product) error {
 	return args.Error(0)
 }
 
+func (m *MockProductRepo) UpdateWithOutbox(product *models.Product, messages []models.OutboxMessage) error {
+	args := m.Called(product, messages)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func (m *MockProductRepo) UpdateProcessingStatus(id uint, status string) error {
 	args := m.Called(id, status)
 	return args.Error(0)
 }
 
-func (m *MockProductRepo) UpdateCompressedImages(id uint, images pq.StringArray) error {
-	args := m.Called(id, images)
-	return args.Error(0)
+func (m *MockProductRepo) MergeImageResults(id uint, results []models.ImageResult) (*models.Product, error) {
+	args := m.Called(id, results)
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) MergeImageResultsWithOutbox(id uint, results []models.ImageResult, messages []models.OutboxMessage) (*models.Product, error) {
+	args := m.Called(id, results, messages)
+	return args.Get(0).(*models.Product), args.Error(1)
 }
 
 type MockCache struct {
@@ -72,6 +101,34 @@ func (m *MockCache) Delete(ctx context.Context, key string) error {
 	return args.Error(0)
 }
 
+func (m *MockCache) BatchGetInto(ctx context.Context, dests map[string]interface{}) ([]string, error) {
+	args := m.Called(ctx, dests)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	args := m.Called(ctx, key, value, expiration, tags)
+	return args.Error(0)
+}
+
+func (m *MockCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	args := m.Called(ctx, tags)
+	return args.Error(0)
+}
+
+type MockImageStore struct {
+	mock.Mock
+}
+
+func (m *MockImageStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	args := m.Called(ctx, key, data, contentType)
+	return args.Error(0)
+}
+
+func (m *MockImageStore) URL(key string) string {
+	return "http://minio:9000/bucket/" + key
+}
+
 type MockPublisher struct {
 	mock.Mock
 }
@@ -81,12 +138,27 @@ func (m *MockPublisher) Publish(queue string, data []byte) error {
 	return args.Error(0)
 }
 
+// queuedTask decodes the single image processing task in messages.
+func queuedTask(t *testing.T, messages []models.OutboxMessage) messaging.ImageProcessingTask {
+	t.Helper()
+	var task messaging.ImageProcessingTask
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "image_processing", messages[0].Queue)
+		var envelope *messaging.Envelope
+		var err error
+		envelope, task, err = messaging.DecodeImageProcessingTask(messages[0].Payload)
+		assert.NoError(t, err)
+		assert.Equal(t, messaging.ImageProcessingTaskVersion, envelope.Version)
+		assert.NotEmpty(t, envelope.ID)
+	}
+	return task
+}
+
 // Test cases
 func TestCreateProduct(t *testing.T) {
 	mockRepo := new(MockProductRepo)
-	mockPublisher := new(MockPublisher)
 	mockCache := new(MockCache)
-	service := services.NewProductService(mockRepo, mockPublisher, mockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
 
 	req := &services.CreateProductRequest{
 		UserID:      1,
@@ -105,62 +177,253 @@ func TestCreateProduct(t *testing.T) {
 		ProcessingStatus:   "pending",
 	}
 
-	mockRepo.On("Create", mock.AnythingOfType("*models.Product")).Return(nil)
-	mockPublisher.On("Publish", "image_processing", mock.Anything).Return(nil)
+	mockRepo.On("CreateWithOutbox", mock.AnythingOfType("*models.Product"), mock.Anything).Return(nil)
+	// The new product belongs in the owner's cached listings
+	mockCache.On("Delete", mock.Anything, "product:0").Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, []string{"user:1"}).Return(nil)
 
-	product, err := service.CreateProduct(req)
+	product, err := service.CreateProduct(services.Actor{UserID: 1}, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, product)
 	assert.Equal(t, expectedProduct.ProductName, product.ProductName)
 	mockRepo.AssertExpectations(t)
-	mockPublisher.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+
+	// The task is written with the product rather than published directly
+	task := queuedTask(t, mockRepo.Calls[0].Arguments.Get(1).([]models.OutboxMessage))
+	assert.Equal(t, []string{"test.jpg"}, task.Images)
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		_, err := service.CreateProduct(services.Actor{UserID: 2}, &services.CreateProductRequest{UserID: 1})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
 }
 
 func TestGetProduct(t *testing.T) {
 	mockRepo := new(MockProductRepo)
-	mockPublisher := new(MockPublisher)
 	mockCache := new(MockCache)
-	service := services.NewProductService(mockRepo, mockPublisher, mockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
 
 	expectedProduct := &models.Product{
 		ID:          1,
+		UserID:      1,
 		ProductName: "Test Product",
 	}
+	owner := services.Actor{UserID: 1}
 
 	t.Run("Cache Hit", func(t *testing.T) {
-		mockCache.On("Get", mock.Anything, "product:1", mock.AnythingOfType("**models.Product")).
-			Run(func(args mock.Arguments) {
-				arg := args.Get(2).(**models.Product) // Use **models.Product
-				*arg = expectedProduct
-			}).
+		mockCache.On("Get", mock.Anything, "product:1", mock.Anything).
+			Run(cachedEntry(services.CacheEntry[*models.Product]{Value: expectedProduct, StaleAt: time.Now().Add(time.Hour)})).
 			Return(nil)
 
-		product, err := service.GetProduct(1)
+		product, err := service.GetProduct(owner, 1)
 		assert.NoError(t, err)
 		assert.NotNil(t, product)
 		assert.Equal(t, expectedProduct.ProductName, product.ProductName)
 	})
 
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		_, err := service.GetProduct(services.Actor{UserID: 2}, 1)
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+
+	t.Run("Admin Override", func(t *testing.T) {
+		product, err := service.GetProduct(services.Actor{UserID: 2, Admin: true}, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedProduct.ID, product.ID)
+	})
+
 	t.Run("Cache Miss", func(t *testing.T) {
-		mockCache.On("Get", mock.Anything, "product:1", mock.AnythingOfType("*models.Product")).
-			Return(fmt.Errorf("cache miss"))
-		mockRepo.On("GetByID", uint(1)).Return(expectedProduct, nil)
-		mockCache.On("Set", mock.Anything, "product:1", expectedProduct, mock.Anything).
+		mockCache.On("Get", mock.Anything, "product:2", mock.Anything).
+			Return(cache.ErrMiss)
+		mockRepo.On("GetByID", uint(2)).Return(expectedProduct, nil)
+		mockCache.On("Set", mock.Anything, "product:2", mock.Anything, mock.Anything).
 			Return(nil)
 
-		product, err := service.GetProduct(1)
+		product, err := service.GetProduct(owner, 2)
 		assert.NoError(t, err)
 		assert.NotNil(t, product)
 		assert.Equal(t, expectedProduct.ProductName, product.ProductName)
+
+		entry := mockCache.Calls[len(mockCache.Calls)-1].Arguments.Get(2).(services.CacheEntry[*models.Product])
+		assert.Equal(t, expectedProduct, entry.Value)
+		// Expiry is jittered by up to 10% of the hour
+		expiration := mockCache.Calls[len(mockCache.Calls)-1].Arguments.Get(3).(time.Duration)
+		assert.LessOrEqual(t, expiration, time.Hour)
+		assert.GreaterOrEqual(t, expiration, 54*time.Minute)
+	})
+}
+
+// cachedEntry makes a mocked Cache.Get return entry.
+func cachedEntry[T any](entry services.CacheEntry[T]) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		*args.Get(2).(*services.CacheEntry[T]) = entry
+	}
+}
+
+func TestGetProductCoalescesLoads(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
+
+	product := &models.Product{ID: 1, UserID: 1}
+	mockCache.On("Get", mock.Anything, "product:1", mock.Anything).Return(cache.ErrMiss)
+	mockCache.On("Set", mock.Anything, "product:1", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetByID", uint(1)).After(100*time.Millisecond).Return(product, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := service.GetProduct(services.Actor{UserID: 1}, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, product, got)
+		}()
+	}
+	wg.Wait()
+
+	mockRepo.AssertNumberOfCalls(t, "GetByID", 1)
+	mockCache.AssertNumberOfCalls(t, "Set", 1)
+}
+
+func TestGetProductStaleWhileRevalidate(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{
+		Jitter:               -1,
+		StaleWhileRevalidate: time.Minute,
+	})
+
+	stale := &models.Product{ID: 1, UserID: 1, ProductName: "Old"}
+	fresh := &models.Product{ID: 1, UserID: 1, ProductName: "New"}
+	mockCache.On("Get", mock.Anything, "product:1", mock.Anything).
+		Run(cachedEntry(services.CacheEntry[*models.Product]{Value: stale, StaleAt: time.Now().Add(-time.Second)})).
+		Return(nil)
+	mockRepo.On("GetByID", uint(1)).Return(fresh, nil)
+
+	refreshed := make(chan mock.Arguments, 1)
+	mockCache.On("Set", mock.Anything, "product:1", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { refreshed <- args }).
+		Return(nil)
+
+	product, err := service.GetProduct(services.Actor{UserID: 1}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Old", product.ProductName)
+
+	select {
+	case args := <-refreshed:
+		entry := args.Get(2).(services.CacheEntry[*models.Product])
+		assert.Equal(t, "New", entry.Value.ProductName)
+		assert.True(t, entry.StaleAt.After(time.Now()))
+		// Kept for the stale window past the TTL
+		assert.Greater(t, args.Get(3).(time.Duration), time.Hour)
+	case <-time.After(time.Second):
+		t.Fatal("stale entry was not refreshed")
+	}
+
+	t.Run("Past Stale Window", func(t *testing.T) {
+		mockCache.On("Get", mock.Anything, "product:2", mock.Anything).
+			Run(cachedEntry(services.CacheEntry[*models.Product]{Value: stale, StaleAt: time.Now().Add(-2 * time.Minute)})).
+			Return(nil)
+		mockRepo.On("GetByID", uint(2)).Return(fresh, nil)
+		mockCache.On("Set", mock.Anything, "product:2", mock.Anything, mock.Anything).Return(nil)
+
+		product, err := service.GetProduct(services.Actor{UserID: 1}, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "New", product.ProductName)
+	})
+}
+
+func TestGetProductCachesNotFound(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{
+		Jitter:      -1,
+		NotFoundTTL: 10 * time.Second,
+	})
+
+	mockCache.On("Get", mock.Anything, "product:9", mock.Anything).Return(cache.ErrMiss).Once()
+	mockRepo.On("GetByID", uint(9)).Return((*models.Product)(nil), gorm.ErrRecordNotFound)
+	mockCache.On("Set", mock.Anything, "product:9", mock.Anything, 10*time.Second).Return(nil)
+
+	_, err := service.GetProduct(services.Actor{UserID: 1}, 9)
+	assert.ErrorIs(t, err, services.ErrProductNotFound)
+
+	entry := mockCache.Calls[len(mockCache.Calls)-1].Arguments.Get(2).(services.CacheEntry[*models.Product])
+	assert.True(t, entry.NotFound)
+
+	// The remembered miss is served without querying again
+	mockCache.On("Get", mock.Anything, "product:9", mock.Anything).Run(cachedEntry(entry)).Return(nil)
+	_, err = service.GetProduct(services.Actor{UserID: 1}, 9)
+	assert.ErrorIs(t, err, services.ErrProductNotFound)
+	mockRepo.AssertNumberOfCalls(t, "GetByID", 1)
+}
+
+func TestGetProducts(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
+
+	cached := &models.Product{ID: 1, UserID: 1}
+	mockCache.On("BatchGetInto", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			dests := args.Get(1).(map[string]interface{})
+			assert.Len(t, dests, 4)
+			*dests["product:1"].(*services.CacheEntry[*models.Product]) = services.CacheEntry[*models.Product]{
+				Value: cached, StaleAt: time.Now().Add(time.Hour),
+			}
+			*dests["product:2"].(*services.CacheEntry[*models.Product]) = services.CacheEntry[*models.Product]{
+				NotFound: true, StaleAt: time.Now().Add(time.Hour),
+			}
+		}).
+		Return([]string{"product:4", "product:3"}, nil).Once()
+	// Misses are loaded in one query and cached, including the missing one
+	mockRepo.On("GetByIDs", []uint{3, 4}).Return([]models.Product{{ID: 3, UserID: 2}}, nil)
+	mockCache.On("Set", mock.Anything, "product:3", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("Set", mock.Anything, "product:4", mock.MatchedBy(func(entry services.CacheEntry[*models.Product]) bool {
+		return entry.NotFound
+	}), mock.Anything).Return(nil)
+
+	lookups, err := service.GetProducts(services.Actor{UserID: 1}, []uint{1, 2, 3, 1, 4})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []services.ProductLookup{
+		{ID: 1, Status: services.LookupOK, Product: cached},
+		{ID: 2, Status: services.LookupNotFound},
+		{ID: 3, Status: services.LookupForbidden},
+		{ID: 4, Status: services.LookupNotFound},
+	}, lookups)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+
+	t.Run("Admin Sees Every Product", func(t *testing.T) {
+		mockCache.On("BatchGetInto", mock.Anything, mock.Anything).Return([]string{"product:3"}, nil).Once()
+		mockRepo.On("GetByIDs", []uint{3}).Return([]models.Product{{ID: 3, UserID: 2}}, nil)
+
+		lookups, err := service.GetProducts(services.Actor{UserID: 1, Admin: true}, []uint{3})
+		assert.NoError(t, err)
+		assert.Equal(t, services.LookupOK, lookups[0].Status)
+	})
+
+	t.Run("Batch Size", func(t *testing.T) {
+		_, err := service.GetProducts(services.Actor{UserID: 1}, nil)
+		assert.ErrorIs(t, err, services.ErrInvalidBatch)
+
+		ids := make([]uint, 101)
+		for i := range ids {
+			ids[i] = uint(i + 1)
+		}
+		_, err = service.GetProducts(services.Actor{UserID: 1}, ids)
+		assert.ErrorIs(t, err, services.ErrInvalidBatch)
 	})
 }
 
 func TestGetFilteredProducts(t *testing.T) {
 	mockRepo := new(MockProductRepo)
-	mockPublisher := new(MockPublisher)
 	mockCache := new(MockCache)
-	service := services.NewProductService(mockRepo, mockPublisher, mockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
 
 	req := &services.FilterProductsRequest{
 		UserID:      1,
@@ -173,27 +436,268 @@ func TestGetFilteredProducts(t *testing.T) {
 		{ID: 1, ProductName: "Test Product", ProductPrice: 50.0},
 	}
 
-	// Cache key for the request
-	cacheKey := fmt.Sprintf("list:%d:minPrice:%f:maxPrice:%f:productName:%s",
+	// Cache key for the request, including the defaulted page parameters
+	cacheKey := fmt.Sprintf("list:%d:minPrice:%f:maxPrice:%f:productName:%s:sort:created_at:limit:20:offset:0:cursor:",
 		req.UserID, req.MinPrice, req.MaxPrice, req.ProductName)
 
 	// Simulate a cache miss
-	mockCache.On("Get", mock.Anything, cacheKey, mock.AnythingOfType("*[]models.Product")).
-		Return(fmt.Errorf("cache miss"))
+	mockCache.On("Get", mock.Anything, cacheKey, mock.Anything).
+		Return(cache.ErrMiss)
 
 	// Simulate database fetch after cache miss
-	mockRepo.On("GetFilteredProducts", req.UserID, req.MinPrice, req.MaxPrice, req.ProductName).
-		Return(expectedProducts, nil)
-
-	// Simulate setting the cache after database fetch
-	mockCache.On("Set", mock.Anything, cacheKey, expectedProducts, mock.Anything).
+	mockRepo.On("GetFilteredProducts", models.ProductFilter{
+		UserID:      req.UserID,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		ProductName: req.ProductName,
+		Sort:        "created_at",
+		Limit:       21,
+	}).Return(expectedProducts, int64(1), nil)
+
+	// Simulate setting the cache after database fetch, tagged with the owner
+	expectedPage := &services.ProductPage{Items: expectedProducts, Total: 1}
+	mockCache.On("SetWithTags", mock.Anything, cacheKey, mock.MatchedBy(func(entry services.CacheEntry[*services.ProductPage]) bool {
+		return assert.ObjectsAreEqual(expectedPage, entry.Value)
+	}), mock.Anything, []string{"user:1"}).
 		Return(nil)
 
-	products, err := service.GetFilteredProducts(req)
+	page, err := service.GetFilteredProducts(services.Actor{UserID: 1}, req)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, expectedProducts[0].ProductName, page.Items[0].ProductName)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestGetFilteredProductsCursor(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
+	actor := services.Actor{UserID: 1}
+
+	mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(cache.ErrMiss)
+	mockCache.On("SetWithTags", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	firstPage := []models.Product{
+		{ID: 4, ProductPrice: 40},
+		{ID: 3, ProductPrice: 30},
+		{ID: 2, ProductPrice: 20},
+	}
+	mockRepo.On("GetFilteredProducts", mock.MatchedBy(func(f models.ProductFilter) bool {
+		return f.After == nil
+	})).Return(firstPage, int64(3), nil).Once()
+
+	page, err := service.GetFilteredProducts(actor, &services.FilterProductsRequest{Sort: "-price", Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, int64(3), page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+
+	mockRepo.On("GetFilteredProducts", mock.MatchedBy(func(f models.ProductFilter) bool {
+		return f.After != nil && f.After.ID == 3 && f.After.Price == 30
+	})).Return(firstPage[2:], int64(3), nil).Once()
+
+	page, err = service.GetFilteredProducts(actor, &services.FilterProductsRequest{Sort: "-price", Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Empty(t, page.NextCursor)
+	mockRepo.AssertExpectations(t)
+
+	t.Run("Cursor From Another Sort", func(t *testing.T) {
+		_, err := service.GetFilteredProducts(actor, &services.FilterProductsRequest{Sort: "name", Cursor: "eyJzIjoiLXByaWNlIiwiaWQiOjN9"})
+		assert.ErrorIs(t, err, services.ErrInvalidFilter)
+	})
+
+	t.Run("Unknown Sort", func(t *testing.T) {
+		_, err := service.GetFilteredProducts(actor, &services.FilterProductsRequest{Sort: "password"})
+		assert.ErrorIs(t, err, services.ErrInvalidFilter)
+	})
+}
+
+func TestPatchProduct(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
+
+	existing := &models.Product{
+		ID:                 1,
+		UserID:             7,
+		ProductName:        "Old Name",
+		ProductDescription: "Old Description",
+		ProductPrice:       10,
+		ProductImages:      pq.StringArray{"a.jpg"},
+		ProcessingStatus:   "completed",
+	}
+
+	mockRepo.On("GetByID", uint(1)).Return(existing, nil)
+	mockRepo.On("UpdateWithOutbox", mock.AnythingOfType("*models.Product"), mock.Anything).Return(nil)
+	mockCache.On("Delete", mock.Anything, "product:1").Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, []string{"user:7"}).Return(nil)
+
+	owner := services.Actor{UserID: 7}
+
+	t.Run("Merge Keeps Unspecified Fields", func(t *testing.T) {
+		product, err := service.PatchProduct(owner, 1, []byte(`{"product_price": 25.5, "product_description": null}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Old Name", product.ProductName)
+		assert.Equal(t, "", product.ProductDescription)
+		assert.Equal(t, 25.5, product.ProductPrice)
+		assert.Equal(t, "completed", product.ProcessingStatus)
+		mockRepo.AssertCalled(t, "UpdateWithOutbox", product, []models.OutboxMessage(nil))
+	})
+
+	t.Run("Changed Images Are Requeued", func(t *testing.T) {
+		product, err := service.PatchProduct(owner, 1, []byte(`{"product_images": ["b.jpg"]}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, pq.StringArray{"b.jpg"}, product.ProductImages)
+		assert.Equal(t, "pending", product.ProcessingStatus)
+
+		calls := mockRepo.Calls
+		task := queuedTask(t, calls[len(calls)-1].Arguments.Get(1).([]models.OutboxMessage))
+		assert.Equal(t, []string{"b.jpg"}, task.Images)
+	})
+
+	t.Run("Invalid Patch", func(t *testing.T) {
+		_, err := service.PatchProduct(owner, 1, []byte(`{"product_name": null}`))
+		assert.ErrorIs(t, err, services.ErrInvalidProduct)
+
+		_, err = service.PatchProduct(owner, 1, []byte(`{"user_id": 2}`))
+		assert.ErrorIs(t, err, services.ErrInvalidProduct)
+	})
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		_, err := service.PatchProduct(services.Actor{UserID: 8}, 1, []byte(`{"product_price": 1}`))
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+}
+
+func TestDeleteProduct(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
+
+	mockRepo.On("GetByID", uint(1)).Return(&models.Product{ID: 1, UserID: 7}, nil)
+	mockRepo.On("Delete", uint(1)).Return(nil)
+	mockCache.On("Delete", mock.Anything, "product:1").Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, []string{"user:7"}).Return(nil)
+
+	err := service.DeleteProduct(services.Actor{UserID: 8}, 1)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+	mockRepo.AssertNotCalled(t, "Delete", uint(1))
+
+	err = service.DeleteProduct(services.Actor{UserID: 7}, 1)
 
 	assert.NoError(t, err)
-	assert.Len(t, products, 1)
-	assert.Equal(t, expectedProducts[0].ProductName, products[0].ProductName)
 	mockRepo.AssertExpectations(t)
 	mockCache.AssertExpectations(t)
 }
+
+func TestAddProductImages(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	mockStore := new(MockImageStore)
+	service := services.NewProductService(mockRepo, mockCache, mockStore, nil, services.ProductCacheConfig{})
+
+	pngHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	mockRepo.On("GetByID", uint(1)).Return(&models.Product{
+		ID:               1,
+		UserID:           7,
+		ProductImages:    pq.StringArray{"http://example.com/a.jpg"},
+		ProcessingStatus: "completed",
+	}, nil)
+	mockStore.On("Put", mock.Anything, mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "products/1/originals/") && strings.HasSuffix(key, ".png")
+	}), pngHeader, "image/png").Return(nil)
+	mockRepo.On("UpdateWithOutbox", mock.AnythingOfType("*models.Product"), mock.Anything).Return(nil)
+	mockCache.On("Delete", mock.Anything, "product:1").Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, []string{"user:7"}).Return(nil)
+
+	t.Run("Stores And Queues Only New Images", func(t *testing.T) {
+		product, err := service.AddProductImages(services.Actor{UserID: 7}, 1, []services.UploadedImage{
+			{Filename: "b.png", Data: pngHeader},
+		})
+
+		assert.NoError(t, err)
+		storedURL := "http://minio:9000/bucket/" + mockStore.Calls[0].Arguments.String(1)
+		assert.Equal(t, pq.StringArray{"http://example.com/a.jpg", storedURL}, product.ProductImages)
+		assert.Equal(t, "pending", product.ProcessingStatus)
+
+		task := queuedTask(t, mockRepo.Calls[1].Arguments.Get(1).([]models.OutboxMessage))
+		assert.Equal(t, []string{storedURL}, task.Images)
+		assert.Equal(t, models.ImageVariants{{Source: storedURL, Status: models.ImageStatusPending}}, product.ImageVariants)
+	})
+
+	t.Run("Rejects Non Image Content", func(t *testing.T) {
+		_, err := service.AddProductImages(services.Actor{UserID: 7}, 1, []services.UploadedImage{
+			{Filename: "evil.png", Data: []byte("<html><script></script></html>")},
+		})
+		assert.ErrorIs(t, err, services.ErrInvalidImage)
+	})
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		_, err := service.AddProductImages(services.Actor{UserID: 8}, 1, []services.UploadedImage{
+			{Filename: "b.png", Data: pngHeader},
+		})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+}
+
+func TestReprocessFailedImages(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	service := services.NewProductService(mockRepo, mockCache, nil, nil, services.ProductCacheConfig{})
+
+	mockRepo.On("GetByID", uint(1)).Return(&models.Product{
+		ID:            1,
+		UserID:        7,
+		ProductImages: pq.StringArray{"a.jpg", "b.jpg", "c.jpg"},
+		ImageVariants: models.ImageVariants{
+			{Source: "a.jpg", Status: models.ImageStatusDone},
+			{Source: "b.jpg", Status: models.ImageStatusFailed, Error: "bad", Attempts: 1},
+			{Source: "c.jpg", Status: models.ImageStatusFailed, Error: "bad", Attempts: 4},
+		},
+		ProcessingStatus: models.ProcessingStatusPartiallyCompleted,
+	}, nil)
+	mockRepo.On("GetByID", uint(2)).Return(&models.Product{
+		ID:               2,
+		UserID:           7,
+		ProductImages:    pq.StringArray{"a.jpg"},
+		ImageVariants:    models.ImageVariants{{Source: "a.jpg", Status: models.ImageStatusDone}},
+		ProcessingStatus: models.ProcessingStatusCompleted,
+	}, nil)
+	mockCache.On("Delete", mock.Anything, "product:1").Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, []string{"user:7"}).Return(nil)
+
+	t.Run("Queues Only Failed Images", func(t *testing.T) {
+		pending := []models.ImageResult{
+			{Source: "b.jpg", Status: models.ImageStatusPending},
+			{Source: "c.jpg", Status: models.ImageStatusPending},
+		}
+		mockRepo.On("MergeImageResultsWithOutbox", uint(1), pending, mock.Anything).
+			Return(&models.Product{ID: 1, UserID: 7, ProcessingStatus: models.ProcessingStatusPending}, nil).Once()
+
+		product, err := service.ReprocessFailedImages(services.Actor{UserID: 7}, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ProcessingStatusPending, product.ProcessingStatus)
+
+		task := queuedTask(t, mockRepo.Calls[1].Arguments.Get(2).([]models.OutboxMessage))
+		assert.Equal(t, []string{"b.jpg", "c.jpg"}, task.Images)
+		assert.Equal(t, uint(1), task.ProductID)
+	})
+
+	t.Run("Nothing To Reprocess", func(t *testing.T) {
+		_, err := service.ReprocessFailedImages(services.Actor{UserID: 7}, 2)
+		assert.ErrorIs(t, err, services.ErrNoFailedImages)
+	})
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		_, err := service.ReprocessFailedImages(services.Actor{UserID: 8}, 1)
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+}
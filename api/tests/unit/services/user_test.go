@@ -2,8 +2,10 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
@@ -32,9 +34,59 @@ func (m *MockUserRepo) GetByID(id uint) (*models.AppUser, error) {
 	return args.Get(0).(*models.AppUser), args.Error(1)
 }
 
+func (m *MockUserRepo) List() ([]models.AppUser, error) {
+	args := m.Called()
+	return args.Get(0).([]models.AppUser), args.Error(1)
+}
+
+func (m *MockUserRepo) Update(user *models.AppUser) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+type MockRefreshTokenRepo struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepo) Create(token *models.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepo) GetByHash(hash string) (*models.RefreshToken, error) {
+	args := m.Called(hash)
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepo) Revoke(id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepo) RevokeFamily(familyID string) error {
+	args := m.Called(familyID)
+	return args.Error(0)
+}
+
+type MockDenylist struct {
+	mock.Mock
+}
+
+func (m *MockDenylist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	args := m.Called(ctx, jti, ttl)
+	return args.Error(0)
+}
+
+func (m *MockDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+var testTokenConfig = services.TokenConfig{Secret: "test-secret"}
+
 func TestCreateUser(t *testing.T) {
 	mockRepo := new(MockUserRepo)
-	service := services.NewUserService(mockRepo, "test-secret")
+	service := services.NewUserService(mockRepo, nil, nil, testTokenConfig)
 
 	t.Run("Successful User Creation", func(t *testing.T) {
 		req := &services.CreateUserRequest{
@@ -75,7 +127,7 @@ func TestCreateUser(t *testing.T) {
 
 func TestValidateCredentials(t *testing.T) {
 	mockRepo := new(MockUserRepo)
-	service := services.NewUserService(mockRepo, "test-secret")
+	service := services.NewUserService(mockRepo, nil, nil, testTokenConfig)
 
 	t.Run("Valid Credentials", func(t *testing.T) {
 		email := "test@example.com"
@@ -132,12 +184,13 @@ func TestValidateCredentials(t *testing.T) {
 }
 
 func TestGenerateToken(t *testing.T) {
-	service := services.NewUserService(new(MockUserRepo), "test-secret")
+	service := services.NewUserService(new(MockUserRepo), nil, nil, testTokenConfig)
 
 	t.Run("Successful Token Generation", func(t *testing.T) {
 		user := &models.AppUser{
 			ID:    1,
 			Email: "test@example.com",
+			Role:  models.RoleViewer,
 		}
 
 		token, err := service.GenerateToken(user)
@@ -157,5 +210,129 @@ func TestGenerateToken(t *testing.T) {
 		assert.True(t, ok)
 		assert.Equal(t, float64(user.ID), claims["user_id"])
 		assert.Equal(t, user.Email, claims["email"])
+		assert.Equal(t, models.RoleViewer, claims["role"])
+		assert.NotEmpty(t, claims["jti"])
+	})
+}
+
+func TestRefreshTokens(t *testing.T) {
+	user := &models.AppUser{ID: 1, Email: "test@example.com"}
+
+	t.Run("Rotates Token Within Family", func(t *testing.T) {
+		userRepo := new(MockUserRepo)
+		tokenRepo := new(MockRefreshTokenRepo)
+		service := services.NewUserService(userRepo, tokenRepo, nil, testTokenConfig)
+
+		var issued *models.RefreshToken
+		tokenRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).
+			Run(func(args mock.Arguments) { issued = args.Get(0).(*models.RefreshToken) }).
+			Return(nil)
+
+		refreshToken, err := service.CreateRefreshToken(user)
+		assert.NoError(t, err)
+		assert.NotEqual(t, refreshToken, issued.TokenHash)
+		familyID := issued.FamilyID
+
+		stored := &models.RefreshToken{ID: 10, UserID: 1, FamilyID: familyID, ExpiresAt: time.Now().Add(time.Hour)}
+		tokenRepo.On("GetByHash", issued.TokenHash).Return(stored, nil)
+		tokenRepo.On("Revoke", uint(10)).Return(true, nil)
+		userRepo.On("GetByID", uint(1)).Return(user, nil)
+
+		tokens, err := service.RefreshTokens(refreshToken)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEqual(t, refreshToken, tokens.RefreshToken)
+		assert.Equal(t, familyID, issued.FamilyID)
+		tokenRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything)
+	})
+
+	t.Run("Reused Token Revokes Family", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepo)
+		service := services.NewUserService(new(MockUserRepo), tokenRepo, nil, testTokenConfig)
+
+		revokedAt := time.Now().Add(-time.Minute)
+		tokenRepo.On("GetByHash", mock.Anything).Return(&models.RefreshToken{
+			ID: 10, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt,
+		}, nil)
+		tokenRepo.On("RevokeFamily", "family").Return(nil)
+
+		tokens, err := service.RefreshTokens("stolen")
+
+		assert.ErrorIs(t, err, services.ErrTokenReused)
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+		assert.Nil(t, tokens)
+		tokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expired Token", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepo)
+		service := services.NewUserService(new(MockUserRepo), tokenRepo, nil, testTokenConfig)
+
+		tokenRepo.On("GetByHash", mock.Anything).Return(&models.RefreshToken{
+			ID: 10, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(-time.Hour),
+		}, nil)
+
+		_, err := service.RefreshTokens("expired")
+
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+		tokenRepo.AssertNotCalled(t, "Revoke", mock.Anything)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	tokenRepo := new(MockRefreshTokenRepo)
+	denylist := new(MockDenylist)
+	service := services.NewUserService(new(MockUserRepo), tokenRepo, denylist, testTokenConfig)
+
+	tokenRepo.On("GetByHash", mock.Anything).Return(&models.RefreshToken{ID: 10, UserID: 1, FamilyID: "family"}, nil)
+	tokenRepo.On("RevokeFamily", "family").Return(nil)
+	denylist.On("Revoke", mock.Anything, "access-jti", mock.MatchedBy(func(ttl time.Duration) bool {
+		return ttl > 0 && ttl <= 10*time.Minute
+	})).Return(nil)
+
+	err := service.Logout(1, "refresh", "access-jti", time.Now().Add(10*time.Minute))
+	assert.NoError(t, err)
+	tokenRepo.AssertExpectations(t)
+	denylist.AssertExpectations(t)
+
+	t.Run("Refresh Token Of Another User", func(t *testing.T) {
+		err := service.Logout(2, "refresh", "other-jti", time.Now().Add(10*time.Minute))
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+}
+
+func TestAssignRole(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	service := services.NewUserService(mockRepo, nil, nil, testTokenConfig)
+	admin := services.Actor{UserID: 1, Admin: true}
+
+	t.Run("Successful Assignment", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(2)).Return(&models.AppUser{ID: 2, Role: models.RoleEditor, Password: "hash"}, nil)
+		mockRepo.On("Update", mock.MatchedBy(func(u *models.AppUser) bool {
+			return u.ID == 2 && u.Role == models.RoleViewer
+		})).Return(nil)
+
+		user, err := service.AssignRole(admin, 2, models.RoleViewer)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.RoleViewer, user.Role)
+		assert.Empty(t, user.Password)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Role", func(t *testing.T) {
+		_, err := service.AssignRole(admin, 2, "superuser")
+		assert.ErrorIs(t, err, services.ErrInvalidRole)
+	})
+
+	t.Run("Own Role", func(t *testing.T) {
+		_, err := service.AssignRole(admin, 1, models.RoleViewer)
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+
+	t.Run("Non Admin", func(t *testing.T) {
+		_, err := service.AssignRole(services.Actor{UserID: 3}, 2, models.RoleAdmin)
+		assert.ErrorIs(t, err, services.ErrForbidden)
 	})
 }
@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockWebhookRepo struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepo) CreateSubscription(subscription *models.WebhookSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) GetSubscription(id uint) (*models.WebhookSubscription, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepo) ListSubscriptions(userID uint) ([]models.WebhookSubscription, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepo) ListSubscribers(userID uint, eventType string) ([]models.WebhookSubscription, error) {
+	args := m.Called(userID, eventType)
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepo) DeleteSubscription(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	args := m.Called(deliveries)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepo) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepo) ListDeliveries(subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(subscriptionID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+type MockEmitter struct {
+	mock.Mock
+}
+
+func (m *MockEmitter) Emit(userID uint, eventType string, data interface{}) error {
+	args := m.Called(userID, eventType, data)
+	return args.Error(0)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	mockRepo := new(MockWebhookRepo)
+	service := services.NewWebhookService(mockRepo, services.WebhookConfig{})
+	mockRepo.On("CreateSubscription", mock.AnythingOfType("*models.WebhookSubscription")).Return(nil)
+
+	t.Run("Generates Secret", func(t *testing.T) {
+		webhook, err := service.CreateSubscription(services.Actor{UserID: 7}, &services.CreateWebhookRequest{
+			URL:    "https://example.com/hooks",
+			Events: []string{models.EventProductCreated},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), webhook.UserID)
+		assert.Len(t, webhook.Secret, 64)
+
+		body, err := json.Marshal(webhook)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), `"secret":"`+webhook.Secret+`"`)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		requests := []services.CreateWebhookRequest{
+			{URL: "ftp://example.com", Events: []string{models.EventProductCreated}},
+			{URL: "/hooks", Events: []string{models.EventProductCreated}},
+			{URL: "https://example.com"},
+			{URL: "https://example.com", Events: []string{"product.sold"}},
+		}
+		for _, req := range requests {
+			_, err := service.CreateSubscription(services.Actor{UserID: 7}, &req)
+			assert.ErrorIs(t, err, services.ErrInvalidWebhook, req)
+		}
+	})
+
+	t.Run("Private Targets", func(t *testing.T) {
+		urls := []string{
+			"http://localhost:8080/hooks",
+			"http://127.0.0.1/hooks",
+			"http://10.0.0.5/hooks",
+			"http://192.168.1.1/hooks",
+			"http://169.254.169.254/latest/meta-data/",
+			"http://[::1]/hooks",
+			"http://[fd00::1]/hooks",
+			"http://[::ffff:127.0.0.1]/hooks",
+			"http://0.0.0.0/hooks",
+		}
+		for _, u := range urls {
+			_, err := service.CreateSubscription(services.Actor{UserID: 7}, &services.CreateWebhookRequest{
+				URL:    u,
+				Events: []string{models.EventProductCreated},
+			})
+			assert.ErrorIs(t, err, services.ErrInvalidWebhook, u)
+		}
+
+		local := services.NewWebhookService(mockRepo, services.WebhookConfig{AllowPrivateTargets: true})
+		_, err := local.CreateSubscription(services.Actor{UserID: 7}, &services.CreateWebhookRequest{
+			URL:    "http://localhost:8080/hooks",
+			Events: []string{models.EventProductCreated},
+		})
+		assert.NoError(t, err)
+	})
+}
+
+func TestGetWebhook(t *testing.T) {
+	mockRepo := new(MockWebhookRepo)
+	service := services.NewWebhookService(mockRepo, services.WebhookConfig{})
+	mockRepo.On("GetSubscription", uint(1)).Return(&models.WebhookSubscription{ID: 1, UserID: 7}, nil)
+	mockRepo.On("GetSubscription", uint(2)).Return((*models.WebhookSubscription)(nil), gorm.ErrRecordNotFound)
+
+	_, err := service.GetSubscription(services.Actor{UserID: 8}, 1)
+	assert.ErrorIs(t, err, services.ErrForbidden)
+
+	_, err = service.GetSubscription(services.Actor{UserID: 7}, 2)
+	assert.ErrorIs(t, err, services.ErrWebhookNotFound)
+
+	webhook, err := service.GetSubscription(services.Actor{UserID: 8, Admin: true}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), webhook.ID)
+}
+
+func TestEmitWebhookEvent(t *testing.T) {
+	mockRepo := new(MockWebhookRepo)
+	service := services.NewWebhookService(mockRepo, services.WebhookConfig{})
+
+	mockRepo.On("ListSubscribers", uint(7), models.EventProductDeleted).Return([]models.WebhookSubscription{{ID: 1}, {ID: 2}}, nil)
+	mockRepo.On("ListSubscribers", uint(8), models.EventProductDeleted).Return([]models.WebhookSubscription{}, nil)
+	mockRepo.On("CreateDeliveries", mock.Anything).Return(nil)
+
+	err := service.Emit(7, models.EventProductDeleted, map[string]uint{"id": 3})
+	assert.NoError(t, err)
+
+	deliveries := mockRepo.Calls[1].Arguments.Get(0).([]models.WebhookDelivery)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, deliveries[0].EventID, deliveries[1].EventID)
+	assert.Equal(t, models.WebhookDeliveryPending, deliveries[0].Status)
+
+	var event services.WebhookEvent
+	assert.NoError(t, json.Unmarshal(deliveries[0].Payload, &event))
+	assert.Equal(t, models.EventProductDeleted, event.Type)
+	assert.Equal(t, deliveries[0].EventID, event.ID)
+
+	// Users without subscriptions queue nothing
+	err = service.Emit(8, models.EventProductDeleted, nil)
+	assert.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "CreateDeliveries", 1)
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	newDelivery := func(attempts int) models.WebhookDelivery {
+		return models.WebhookDelivery{
+			ID:        1,
+			EventID:   "event-1",
+			EventType: models.EventProductCreated,
+			Payload:   models.RawJSON(`{"id":"event-1"}`),
+			Status:    models.WebhookDeliveryPending,
+			Attempts:  attempts,
+			Subscription: models.WebhookSubscription{
+				ID:     1,
+				URL:    server.URL,
+				Events: pq.StringArray{models.EventProductCreated},
+				Secret: "secret",
+				Active: true,
+			},
+		}
+	}
+	deliver := func(delivery models.WebhookDelivery) *models.WebhookDelivery {
+		mockRepo := new(MockWebhookRepo)
+		// The test server listens on loopback
+		service := services.NewWebhookService(mockRepo, services.WebhookConfig{MaxAttempts: 3, Backoff: time.Minute, AllowPrivateTargets: true})
+		mockRepo.On("ClaimDueDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil)
+		mockRepo.On("UpdateDelivery", mock.Anything).Return(nil)
+
+		n, err := service.DeliverDue(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		return mockRepo.Calls[1].Arguments.Get(0).(*models.WebhookDelivery)
+	}
+
+	t.Run("Signed Delivery Succeeds", func(t *testing.T) {
+		status = http.StatusNoContent
+		delivery := deliver(newDelivery(0))
+
+		assert.Equal(t, models.WebhookDeliverySucceeded, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.NotNil(t, delivery.DeliveredAt)
+
+		assert.Equal(t, models.EventProductCreated, received.Header.Get(services.WebhookHeaderEvent))
+		assert.Equal(t, "event-1", received.Header.Get(services.WebhookHeaderID))
+		timestamp, err := strconv.ParseInt(received.Header.Get(services.WebhookHeaderTimestamp), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, "sha256="+services.SignWebhook("secret", timestamp, receivedBody),
+			received.Header.Get(services.WebhookHeaderSignature))
+		assert.JSONEq(t, `{"id":"event-1"}`, string(receivedBody))
+	})
+
+	t.Run("Failure Backs Off", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		delivery := deliver(newDelivery(1))
+
+		assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+		assert.Equal(t, 2, delivery.Attempts)
+		assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+		assert.Contains(t, delivery.LastError, "500")
+		assert.WithinDuration(t, time.Now().Add(2*time.Minute), delivery.NextAttemptAt, 5*time.Second)
+	})
+
+	t.Run("Out Of Attempts Fails", func(t *testing.T) {
+		status = http.StatusBadGateway
+		delivery := deliver(newDelivery(2))
+
+		assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+		assert.Equal(t, 3, delivery.Attempts)
+	})
+
+	t.Run("Private Targets Refused When Connecting", func(t *testing.T) {
+		received = nil
+		mockRepo := new(MockWebhookRepo)
+		service := services.NewWebhookService(mockRepo, services.WebhookConfig{MaxAttempts: 3, Backoff: time.Minute})
+		mockRepo.On("ClaimDueDeliveries", mock.Anything, mock.Anything).Return([]models.WebhookDelivery{newDelivery(0)}, nil)
+		mockRepo.On("UpdateDelivery", mock.Anything).Return(nil)
+
+		_, err := service.DeliverDue(context.Background())
+		assert.NoError(t, err)
+
+		delivery := mockRepo.Calls[1].Arguments.Get(0).(*models.WebhookDelivery)
+		assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+		assert.Contains(t, delivery.LastError, "private or local address")
+		assert.Nil(t, received)
+	})
+}
+
+func TestProductServiceEmitsEvents(t *testing.T) {
+	mockRepo := new(MockProductRepo)
+	mockCache := new(MockCache)
+	mockEmitter := new(MockEmitter)
+	service := services.NewProductService(mockRepo, mockCache, nil, mockEmitter, services.ProductCacheConfig{})
+
+	mockRepo.On("CreateWithOutbox", mock.AnythingOfType("*models.Product"), mock.Anything).Return(nil)
+	mockRepo.On("GetByID", uint(0)).Return(&models.Product{UserID: 7}, nil)
+	mockRepo.On("Delete", uint(0)).Return(nil)
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+	mockEmitter.On("Emit", uint(7), mock.Anything, mock.AnythingOfType("services.ProductEvent")).Return(nil)
+
+	_, err := service.CreateProduct(services.Actor{UserID: 7}, &services.CreateProductRequest{Name: "Lamp", Price: 10})
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteProduct(services.Actor{UserID: 7}, 0))
+
+	mockEmitter.AssertCalled(t, "Emit", uint(7), models.EventProductCreated, mock.Anything)
+	mockEmitter.AssertCalled(t, "Emit", uint(7), models.EventProductDeleted, mock.Anything)
+}
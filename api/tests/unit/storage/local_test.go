@@ -0,0 +1,87 @@
+// api/tests/unit/storage/local_test.go
+package tests
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.New(storage.Config{
+		Backend: storage.BackendLocal,
+		Local: storage.LocalConfig{
+			Root:      t.TempDir(),
+			PublicURL: "http://localhost:9000/files/",
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("Put And Get", func(t *testing.T) {
+		err := store.Put(ctx, "products/1/originals/a.png", []byte("png"), "image/png")
+		require.NoError(t, err)
+
+		body, err := store.Get(ctx, "products/1/originals/a.png")
+		require.NoError(t, err)
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		assert.Equal(t, "png", string(data))
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		info, err := store.Stat(ctx, "products/1/originals/a.png")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), info.Size)
+		assert.Equal(t, "image/png", info.ContentType)
+
+		_, err = store.Stat(ctx, "products/1/originals/missing.png")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("List By Prefix", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "products/2/originals/b.jpg", []byte("jpg"), "image/jpeg"))
+
+		objects, err := store.List(ctx, "products/1/")
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		assert.Equal(t, "products/1/originals/a.png", objects[0].Key)
+	})
+
+	t.Run("URL Round Trip", func(t *testing.T) {
+		url := store.URL("products/1/originals/a.png")
+		assert.Equal(t, "http://localhost:9000/files/products/1/originals/a.png", url)
+
+		key, ok := storage.KeyFromURL(store, url)
+		assert.True(t, ok)
+		assert.Equal(t, "products/1/originals/a.png", key)
+
+		_, ok = storage.KeyFromURL(store, "http://example.com/a.png")
+		assert.False(t, ok)
+
+		presigned, err := store.PresignGet(ctx, "products/1/originals/a.png", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, url, presigned)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, store.Delete(ctx, "products/2/originals/b.jpg"))
+		_, err := store.Get(ctx, "products/2/originals/b.jpg")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
+		// Deleting a missing object is not an error
+		assert.NoError(t, store.Delete(ctx, "products/2/originals/b.jpg"))
+	})
+
+	t.Run("Rejects Keys Outside Root", func(t *testing.T) {
+		for _, key := range []string{"", "../escape", "a/../../escape", "/abs"} {
+			err := store.Put(ctx, key, []byte("x"), "text/plain")
+			assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KPVISHNUSAI/product-management-system/api/repository/postgres"
+	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/image-processor/config"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/database"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+)
+
+const dlqUsage = `usage: image-processor dlq <command> [flags]
+
+Commands:
+  list                 list dead-lettered tasks
+  inspect <id>         show one dead-lettered task
+  replay               requeue matching tasks and reset their products to pending
+  purge                delete matching tasks
+
+Flags select messages for list, replay and purge:
+`
+
+// runDeadLetterCommand implements the dlq subcommand and returns the exit
+// code.
+func runDeadLetterCommand(cfg *config.Config, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dlq", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ids := flags.String("id", "", "comma separated message IDs")
+	productID := flags.Uint("product-id", 0, "product ID")
+	errorClass := flags.String("error-class", "", "error class, e.g. invalid_image or timeout")
+	all := flags.Bool("all", false, "allow replay or purge of every message")
+	limit := flags.Int("limit", 100, "maximum number of messages to list, 0 for all")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, dlqUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+	command := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	filter := services.DeadLetterFilter{
+		ProductID:  *productID,
+		ErrorClass: *errorClass,
+		All:        *all,
+	}
+	if *ids != "" {
+		filter.IDs = strings.Split(*ids, ",")
+	}
+
+	service, closeClients, err := newDeadLetterService(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer closeClients()
+
+	var result interface{}
+	switch command {
+	case "list":
+		result, err = service.ListDeadLetters(filter, *limit)
+	case "inspect":
+		if flags.NArg() != 1 {
+			flags.Usage()
+			return 2
+		}
+		result, err = service.GetDeadLetter(flags.Arg(0))
+	case "replay":
+		var replayed []messaging.DeadLetter
+		replayed, err = service.ReplayDeadLetters(filter)
+		result = map[string]interface{}{"replayed": replayed}
+	case "purge":
+		var purged int
+		purged, err = service.PurgeDeadLetters(filter)
+		result = map[string]int{"purged": purged}
+	default:
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func newDeadLetterService(cfg *config.Config) (*services.DeadLetterService, func(), error) {
+	// An in-memory dead letter queue lives and dies with the process that
+	// filled it
+	if cfg.Broker.Backend == messaging.BrokerMemory {
+		return nil, nil, fmt.Errorf("the dlq command needs RabbitMQ, not the %s broker", cfg.Broker.Backend)
+	}
+
+	db, err := database.NewPostgresDB(cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mqClient, err := messaging.NewRabbitMQClient(cfg.RabbitMQ.URL, messaging.Config{
+		Topology:       messaging.ImageProcessingTopology(nil),
+		ConfirmTimeout: cfg.RabbitMQ.ConfirmTimeout,
+	})
+	if err != nil {
+		database.Close(db)
+		return nil, nil, err
+	}
+
+	redisClient, err := cache.NewRedisCache(redisConfig(cfg))
+	if err != nil {
+		database.Close(db)
+		mqClient.Close()
+		return nil, nil, err
+	}
+	// Replays reset products, which the API replicas may hold locally
+	productCache, err := cache.NewTieredCache(redisClient, cache.TieredConfig{})
+	if err != nil {
+		database.Close(db)
+		mqClient.Close()
+		redisClient.Close()
+		return nil, nil, err
+	}
+
+	productRepo := postgres.NewProductRepository(db)
+	service := services.NewDeadLetterService(
+		messaging.NewDeadLetterQueue(mqClient, messaging.ImageProcessingDLQ, messaging.ImageProcessingQueue),
+		productRepo,
+		services.NewProductService(productRepo, productCache, nil, nil, services.ProductCacheConfig{}),
+	)
+	closeClients := func() {
+		database.Close(db)
+		mqClient.Close()
+		productCache.Close()
+		redisClient.Close()
+	}
+	return service, closeClients, nil
+}
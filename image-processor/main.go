@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/KPVISHNUSAI/product-management-system/api/repository/postgres"
 	"github.com/KPVISHNUSAI/product-management-system/api/services"
@@ -11,9 +19,7 @@ import (
 	"github.com/KPVISHNUSAI/product-management-system/pkg/cache"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/database"
 	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/storage"
 )
 
 func main() {
@@ -22,11 +28,31 @@ func main() {
 		panic(err)
 	}
 
-	// Initialize AWS session
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("ap-southeast-2")},
-	)
-	s3Client := s3.New(sess)
+	// "image-processor dlq ..." manages the dead letter queue and exits
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		os.Exit(runDeadLetterCommand(cfg, os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	// Initialize image storage
+	store, err := storage.New(storage.Config{
+		Backend: cfg.Storage.Backend,
+		S3: storage.S3Config{
+			Region:         cfg.AWS.Region,
+			Bucket:         cfg.AWS.Bucket,
+			AccessKey:      cfg.AWS.AccessKey,
+			SecretKey:      cfg.AWS.SecretKey,
+			Endpoint:       cfg.AWS.Endpoint,
+			PublicURL:      cfg.AWS.PublicURL,
+			ForcePathStyle: cfg.AWS.ForcePathStyle,
+		},
+		Local: storage.LocalConfig{
+			Root:      cfg.Storage.LocalRoot,
+			PublicURL: cfg.Storage.PublicURL,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
 
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName)
@@ -34,41 +60,160 @@ func main() {
 		panic(err)
 	}
 
+	variants, err := processor.ParseVariantProfiles(cfg.Images.Variants)
+	if err != nil {
+		panic(err)
+	}
+	formats, err := processor.ParseOutputFormats(cfg.Images.OutputFormats)
+	if err != nil {
+		panic(err)
+	}
+
 	// Initialize components
-	imageProcessor := processor.NewImageProcessor(s3Client, cfg.AWS.Bucket)
+	imageProcessor := processor.NewImageProcessor(store, processor.Config{
+		Variants:         variants,
+		Formats:          formats,
+		MaxDownloadBytes: cfg.Images.MaxDownloadBytes,
+		MaxPixels:        cfg.Images.MaxPixels,
+	})
 	productRepo := postgres.NewProductRepository(db)
 
 	// Add these lines
-	redisClient, err := cache.NewRedisCache(
-		fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
-		cfg.Redis.Password,
-	)
+	redisClient, err := cache.NewRedisCache(redisConfig(cfg))
+	if err != nil {
+		panic(err)
+	}
+
+	// Events are only queued here, the API delivers them
+	webhookService := services.NewWebhookService(postgres.NewWebhookRepository(db), services.WebhookConfig{})
+	// Invalidations go through the tiered cache so API replicas drop their
+	// local copies
+	productCache, err := cache.NewTieredCache(redisClient, cache.TieredConfig{})
 	if err != nil {
 		panic(err)
 	}
+	productService := services.NewProductService(productRepo, productCache, nil, webhookService, services.ProductCacheConfig{})
+	eventService := services.NewProcessingEventService(cache.NewEventStream(redisClient, cache.StreamConfig{
+		MaxLen: cfg.Events.StreamMaxLen,
+		TTL:    cfg.Events.StreamTTL,
+	}))
 
-	mqClient, err := messaging.NewRabbitMQClient(cfg.RabbitMQ.URL)
+	retryDelays, err := queue.ParseRetryDelays(cfg.Workers.RetryDelays)
 	if err != nil {
 		panic(err)
 	}
+	retry := queue.RetryPolicy{
+		Delays:      retryDelays,
+		MaxAttempts: cfg.Workers.MaxAttempts,
+	}
 
-	productService := services.NewProductService(productRepo, mqClient, redisClient)
+	var broker interface {
+		queue.Broker
+		Close() error
+	}
+	switch cfg.Broker.Backend {
+	case messaging.BrokerRabbitMQ, "":
+		// The client declares the task, retry and dead letter queues and
+		// reconnects on its own when the connection drops
+		broker, err = messaging.NewRabbitMQClient(cfg.RabbitMQ.URL, messaging.Config{
+			Topology:        queue.Topology(retry),
+			ConfirmTimeout:  cfg.RabbitMQ.ConfirmTimeout,
+			ChannelPoolSize: cfg.RabbitMQ.ChannelPoolSize,
+		})
+		if err != nil {
+			panic(err)
+		}
+	case messaging.BrokerMemory:
+		// Only tasks published in this process, i.e. retries, reach the
+		// consumer
+		log.Printf("Using the in-memory broker, tasks queued by the API are not received")
+		broker = messaging.NewMemoryBroker(queue.Topology(retry))
+	default:
+		panic(fmt.Sprintf("unknown broker backend %q", cfg.Broker.Backend))
+	}
 
 	// Initialize consumer
 	consumer, err := queue.NewConsumer(
-		cfg.RabbitMQ.URL,
+		broker,
 		imageProcessor,
 		productRepo,
 		productService,
+		eventService,
+		queue.Config{
+			Workers:          cfg.Workers.Count,
+			ImageConcurrency: cfg.Workers.ImageConcurrency,
+			TaskTimeout:      cfg.Workers.TaskTimeout,
+			Retry:            retry,
+		},
 	)
 	if err != nil {
 		panic(err)
 	}
 
+	// Expose worker metrics at /debug/vars
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		metricsServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Start consuming messages
 	if err := consumer.Start(); err != nil {
 		panic(err)
 	}
 
-	select {}
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down, waiting up to %s for in-flight tasks", cfg.Shutdown.Timeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+
+	if err := consumer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Unfinished tasks were requeued: %v", err)
+	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+
+	// Close clients in dependency order
+	if err := database.Close(db); err != nil {
+		log.Printf("Failed to close database: %v", err)
+	}
+	if err := productCache.Close(); err != nil {
+		log.Printf("Failed to close cache: %v", err)
+	}
+	if err := redisClient.Close(); err != nil {
+		log.Printf("Failed to close Redis: %v", err)
+	}
+	if err := broker.Close(); err != nil {
+		log.Printf("Failed to close broker: %v", err)
+	}
+}
+
+func redisConfig(cfg *config.Config) cache.RedisConfig {
+	return cache.RedisConfig{
+		Mode:             cfg.Redis.Mode,
+		Addrs:            cfg.Redis.Addrs,
+		MasterName:       cfg.Redis.MasterName,
+		Username:         cfg.Redis.Username,
+		Password:         cfg.Redis.Password,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		DB:               cfg.Redis.DB,
+		TLS:              cfg.Redis.TLS,
+		PoolSize:         cfg.Redis.PoolSize,
+		MinIdleConns:     cfg.Redis.MinIdleConns,
+		DialTimeout:      cfg.Redis.DialTimeout,
+		ReadTimeout:      cfg.Redis.ReadTimeout,
+		WriteTimeout:     cfg.Redis.WriteTimeout,
+	}
 }
@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// OriginalFormat stands for the format of the source image.
+const OriginalFormat = "original"
+
+var outputFormats = map[string]bool{
+	OriginalFormat: true,
+	"jpeg":         true,
+	"png":          true,
+	"webp":         true,
+}
+
+// ParseOutputFormats parses a comma separated list of output formats, e.g.
+// "original,webp". The first format becomes the primary file of each variant.
+func ParseOutputFormats(spec string) ([]string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+
+	for _, format := range strings.Split(spec, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		if !outputFormats[format] {
+			return nil, fmt.Errorf("unsupported output format %q", format)
+		}
+		if !seen[format] {
+			seen[format] = true
+			formats = append(formats, format)
+		}
+	}
+
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no output formats configured")
+	}
+	return formats, nil
+}
+
+// resolveFormats replaces OriginalFormat with the source format and drops
+// formats that end up listed twice.
+func resolveFormats(formats []string, source string) []string {
+	resolved := make([]string, 0, len(formats))
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		if format == OriginalFormat {
+			format = source
+		}
+		if !seen[format] {
+			seen[format] = true
+			resolved = append(resolved, format)
+		}
+	}
+	return resolved
+}
+
+// encode writes img in format. Quality only applies to JPEG: PNG is lossless
+// and the pure Go WebP encoder only writes lossless WebP.
+func encode(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case "png":
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, img)
+	case "webp":
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("unsupported image format: %s", format)
+	}
+	return buf.Bytes(), err
+}
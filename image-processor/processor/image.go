@@ -2,86 +2,187 @@ package processor
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"image/png"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/storage"
 	"github.com/google/uuid"
 )
 
+// ErrInvalidImage marks source images that will never process, so retrying
+// them is pointless.
+var ErrInvalidImage = errors.New("invalid image")
+
 type ImageProcessor struct {
-	s3Client *s3.S3
-	bucket   string
+	store storage.Blob
+	cfg   Config
+}
+
+// Config controls what the processor produces and bounds the memory one
+// image may take. Zero limits fall back to the defaults below.
+type Config struct {
+	Variants []VariantProfile
+	Formats  []string
+	// MaxDownloadBytes caps the size of a source image file.
+	MaxDownloadBytes int64
+	// MaxPixels caps the decoded size of a source image.
+	MaxPixels int
 }
 
-func NewImageProcessor(s3Client *s3.S3, bucket string) *ImageProcessor {
+const (
+	defaultMaxDownloadBytes = 20 << 20
+	defaultMaxPixels        = 50_000_000
+)
+
+func NewImageProcessor(store storage.Blob, cfg Config) *ImageProcessor {
+	if cfg.MaxDownloadBytes <= 0 {
+		cfg.MaxDownloadBytes = defaultMaxDownloadBytes
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = defaultMaxPixels
+	}
+
 	return &ImageProcessor{
-		s3Client: s3Client,
-		bucket:   bucket,
+		store: store,
+		cfg:   cfg,
 	}
 }
 
-func (p *ImageProcessor) ProcessImage(imageURL string) (string, error) {
+// ProcessImage renders every configured variant of the image at imageURL in
+// every output format and stores them next to each other.
+func (p *ImageProcessor) ProcessImage(ctx context.Context, imageURL string) (models.ProcessedImage, error) {
+	result := models.ProcessedImage{Source: imageURL}
+
 	// Download image
-	resp, err := http.Get(imageURL)
+	data, err := p.download(ctx, imageURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to download image: %w", err)
+		return result, fmt.Errorf("failed to download image: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Read content type
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "image/") {
-		return "", fmt.Errorf("invalid content type: %s", contentType)
+	// Check the dimensions before decoding so a small file can't expand
+	// into a huge bitmap
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to decode image: %v", ErrInvalidImage, err)
+	}
+	if format != "jpeg" && format != "png" {
+		return result, fmt.Errorf("%w: unsupported image format: %s", ErrInvalidImage, format)
+	}
+	if config.Width*config.Height > p.cfg.MaxPixels {
+		return result, fmt.Errorf("%w: image too large: %dx%d pixels", ErrInvalidImage, config.Width, config.Height)
 	}
 
 	// Decode image
-	img, format, err := image.Decode(resp.Body)
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return result, fmt.Errorf("%w: failed to decode image: %v", ErrInvalidImage, err)
 	}
 
-	// Compress image
-	var buf bytes.Buffer
-	switch format {
-	case "jpeg", "jpg":
-		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60})
-	case "png":
-		err = png.Encode(&buf, img)
-	default:
-		return "", fmt.Errorf("unsupported image format: %s", format)
+	// All variants of one source share a directory
+	dir := fmt.Sprintf("variants/%s", uuid.New().String())
+	formats := resolveFormats(p.cfg.Formats, format)
+
+	for _, profile := range p.cfg.Variants {
+		resized := profile.Resize(img)
+		bounds := resized.Bounds()
+		variant := models.ImageVariant{
+			Name:   profile.Name,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+		}
+
+		for _, outputFormat := range formats {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			data, err := encode(resized, outputFormat, profile.Quality)
+			if err != nil {
+				return result, fmt.Errorf("failed to encode %s variant as %s: %w", profile.Name, outputFormat, err)
+			}
+
+			key := fmt.Sprintf("%s/%s.%s", dir, profile.Name, outputFormat)
+			if err := p.store.Put(ctx, key, data, "image/"+outputFormat); err != nil {
+				return result, fmt.Errorf("failed to upload %s variant: %w", profile.Name, err)
+			}
+
+			variant.Formats = append(variant.Formats, models.ImageFile{
+				Format: outputFormat,
+				URL:    p.store.URL(key),
+				Size:   int64(len(data)),
+			})
+		}
+
+		// The first configured format is the primary file
+		variant.Format = variant.Formats[0].Format
+		variant.URL = variant.Formats[0].URL
+		result.Variants = append(result.Variants, variant)
 	}
 
+	return result, nil
+}
+
+// download reads a source image into memory, refusing files larger than
+// MaxDownloadBytes.
+func (p *ImageProcessor) download(ctx context.Context, imageURL string) ([]byte, error) {
+	body, err := p.open(ctx, imageURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to compress image: %w", err)
+		return nil, err
 	}
+	defer body.Close()
 
-	// Generate unique filename
-	filename := fmt.Sprintf("%d-%s.%s", time.Now().UnixNano(), uuid.New().String(), format)
-	key := fmt.Sprintf("compressed/%s", filename)
+	data, err := io.ReadAll(io.LimitReader(body, p.cfg.MaxDownloadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.cfg.MaxDownloadBytes {
+		return nil, fmt.Errorf("%w: image exceeds %d bytes", ErrInvalidImage, p.cfg.MaxDownloadBytes)
+	}
+	return data, nil
+}
 
-	// Upload to S3
-	_, err = p.s3Client.PutObject(&s3.PutObjectInput{
-		Bucket:      aws.String(p.bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(buf.Bytes()),
-		ContentType: aws.String(fmt.Sprintf("image/%s", format)),
-	})
+// open reads images that live in our own store straight from it, so uploaded
+// originals don't need to be publicly reachable, and fetches anything else
+// over HTTP.
+func (p *ImageProcessor) open(ctx context.Context, imageURL string) (io.ReadCloser, error) {
+	if key, ok := storage.KeyFromURL(p.store, imageURL); ok {
+		body, err := p.store.Get(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		return body, err
+	}
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %w", err)
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		// Missing or forbidden images won't appear on their own
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, fmt.Errorf("%w: unexpected status: %s", ErrInvalidImage, resp.Status)
+		}
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
 	}
 
-	return fmt.Sprintf("s3://%s/%s", p.bucket, key), nil
-}
-
-func generateFileName(imageURL string) string {
-	// Generate a unique filename using timestamp and random string
-	return fmt.Sprintf("%d-%s.jpg", time.Now().UnixNano(), uuid.New().String())
+	// Read content type
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: invalid content type: %s", ErrInvalidImage, contentType)
+	}
+	return resp.Body, nil
 }
@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// FitMode scales the image down to fit inside the box, keeping its aspect
+	// ratio.
+	FitMode = "fit"
+	// CropMode scales the image to cover the box and crops the overflow
+	// around the centre.
+	CropMode = "crop"
+)
+
+// VariantProfile describes one rendition generated for every source image.
+// A zero MaxWidth or MaxHeight leaves that dimension unbounded. Images are
+// never upscaled.
+type VariantProfile struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+	Mode      string
+	Quality   int
+}
+
+// ParseVariantProfiles parses a comma separated list of
+// name:WIDTHxHEIGHT:mode:quality profiles, e.g. "thumbnail:150x150:crop:70".
+func ParseVariantProfiles(spec string) ([]VariantProfile, error) {
+	var profiles []VariantProfile
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("invalid variant profile %q: want name:WIDTHxHEIGHT:mode:quality", entry)
+		}
+
+		profile := VariantProfile{Name: parts[0], Mode: parts[2]}
+		if profile.Name == "" || seen[profile.Name] {
+			return nil, fmt.Errorf("invalid variant profile %q: name must be unique and non-empty", entry)
+		}
+		seen[profile.Name] = true
+
+		width, height, ok := strings.Cut(parts[1], "x")
+		if !ok {
+			return nil, fmt.Errorf("invalid variant profile %q: bad size %q", entry, parts[1])
+		}
+		var err error
+		if profile.MaxWidth, err = strconv.Atoi(width); err != nil || profile.MaxWidth < 0 {
+			return nil, fmt.Errorf("invalid variant profile %q: bad width %q", entry, width)
+		}
+		if profile.MaxHeight, err = strconv.Atoi(height); err != nil || profile.MaxHeight < 0 {
+			return nil, fmt.Errorf("invalid variant profile %q: bad height %q", entry, height)
+		}
+
+		switch profile.Mode {
+		case FitMode:
+		case CropMode:
+			if profile.MaxWidth == 0 || profile.MaxHeight == 0 {
+				return nil, fmt.Errorf("invalid variant profile %q: crop needs both width and height", entry)
+			}
+		default:
+			return nil, fmt.Errorf("invalid variant profile %q: unknown mode %q", entry, profile.Mode)
+		}
+
+		if profile.Quality, err = strconv.Atoi(parts[3]); err != nil || profile.Quality < 1 || profile.Quality > 100 {
+			return nil, fmt.Errorf("invalid variant profile %q: quality must be 1-100", entry)
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no variant profiles configured")
+	}
+	return profiles, nil
+}
+
+// Resize renders src according to the profile.
+func (p VariantProfile) Resize(src image.Image) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	if p.Mode == CropMode {
+		return p.crop(src, srcW, srcH)
+	}
+
+	scale := 1.0
+	if p.MaxWidth > 0 && srcW > p.MaxWidth {
+		scale = float64(p.MaxWidth) / float64(srcW)
+	}
+	if p.MaxHeight > 0 && srcH > p.MaxHeight {
+		scale = min(scale, float64(p.MaxHeight)/float64(srcH))
+	}
+	if scale == 1.0 {
+		return src
+	}
+
+	dstW := max(1, int(float64(srcW)*scale+0.5))
+	dstH := max(1, int(float64(srcH)*scale+0.5))
+	return scaleTo(src, bounds, dstW, dstH)
+}
+
+func (p VariantProfile) crop(src image.Image, srcW, srcH int) image.Image {
+	// Without upscaling a small source can't fill the box, so shrink the box
+	dstW, dstH := min(p.MaxWidth, srcW), min(p.MaxHeight, srcH)
+
+	// Take the largest centred region with the box's aspect ratio
+	cropW, cropH := srcW, srcW*dstH/dstW
+	if cropH > srcH {
+		cropW, cropH = srcH*dstW/dstH, srcH
+	}
+	origin := src.Bounds().Min
+	x0 := origin.X + (srcW-cropW)/2
+	y0 := origin.Y + (srcH-cropH)/2
+	region := image.Rect(x0, y0, x0+cropW, y0+cropH)
+
+	return scaleTo(src, region, dstW, dstH)
+}
+
+func scaleTo(src image.Image, region image.Rectangle, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, region, draw.Src, nil)
+	return dst
+}
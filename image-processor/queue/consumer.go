@@ -2,207 +2,579 @@
 package queue
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"log"
+	"sync"
 	"time"
 
-	"github.com/KPVISHNUSAI/product-management-system/api/repository/postgres"
-	"github.com/KPVISHNUSAI/product-management-system/api/services"
+	"github.com/KPVISHNUSAI/product-management-system/api/models"
 	"github.com/KPVISHNUSAI/product-management-system/image-processor/processor"
-	"github.com/lib/pq"
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
 )
 
+// Broker is the messaging the consumer relies on. It is implemented by
+// messaging.RabbitMQClient and, for tests and local runs, by
+// messaging.MemoryBroker.
+type Broker interface {
+	Subscribe(queue, tag string, prefetch int) messaging.Subscription
+	PublishMessage(ctx context.Context, queue string, msg amqp.Publishing) error
+}
+
+// ImageProcessor turns a source image into its stored variants. It is
+// implemented by processor.ImageProcessor.
+type ImageProcessor interface {
+	ProcessImage(ctx context.Context, imageURL string) (models.ProcessedImage, error)
+}
+
+// ProductRepository records image results on products.
+type ProductRepository interface {
+	MergeImageResults(id uint, results []models.ImageResult) (*models.Product, error)
+}
+
+// ProductService is the part of services.ProductService the consumer
+// notifies of changed products.
+type ProductService interface {
+	InvalidateCache(product *models.Product) error
+	ProductProcessed(product *models.Product)
+}
+
+// StatusPublisher announces the processing status of products. It is
+// implemented by services.ProcessingEventService.
+type StatusPublisher interface {
+	PublishProcessingStatus(product *models.Product) error
+}
+
 type Consumer struct {
-	conn           *amqp.Connection
-	channel        *amqp.Channel
-	imageProcessor *processor.ImageProcessor
-	productRepo    *postgres.ProductRepository
-	productService *services.ProductService
+	broker         Broker
+	subscription   messaging.Subscription
+	imageProcessor ImageProcessor
+	productRepo    ProductRepository
+	productService ProductService
+	events         StatusPublisher
+	cfg            Config
 	queueName      string
 	dlqName        string
+	consumerTag    string
+
+	// stopping is closed when Shutdown begins; cancelTasks aborts the tasks
+	// still running once the shutdown deadline passes.
+	stopping    chan struct{}
+	tasksCtx    context.Context
+	cancelTasks context.CancelFunc
+	workers     sync.WaitGroup
 }
 
-type ImageProcessingTask struct {
-	ProductID uint     `json:"product_id"`
-	Images    []string `json:"images"`
+// Config sizes the worker pool. Zero values fall back to the defaults below.
+type Config struct {
+	// Workers is the number of tasks processed at once, which is also the
+	// AMQP prefetch count.
+	Workers int
+	// ImageConcurrency is the number of images of one task processed at once.
+	ImageConcurrency int
+	// TaskTimeout bounds the time spent on one attempt at a task.
+	TaskTimeout time.Duration
+	// Retry schedules failed attempts.
+	Retry RetryPolicy
 }
 
-func NewConsumer(amqpURL string, imageProcessor *processor.ImageProcessor, productRepo *postgres.ProductRepository, productService *services.ProductService) (*Consumer, error) {
-	conn, err := amqp.Dial(amqpURL)
-	if err != nil {
-		return nil, err
-	}
+const (
+	defaultWorkers          = 4
+	defaultImageConcurrency = 4
+	defaultTaskTimeout      = 5 * time.Minute
 
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, err
+	// handOnAttempts bounds how often failed images are published to a
+	// retry or dead letter queue before giving up, handOnDelay is the pause
+	// after the first refusal, doubling after each further one.
+	handOnAttempts = 3
+	handOnDelay    = 100 * time.Millisecond
+)
+
+func NewConsumer(broker Broker, imageProcessor ImageProcessor, productRepo ProductRepository, productService ProductService, events StatusPublisher, cfg Config) (*Consumer, error) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.ImageConcurrency <= 0 {
+		cfg.ImageConcurrency = defaultImageConcurrency
 	}
+	if cfg.TaskTimeout <= 0 {
+		cfg.TaskTimeout = defaultTaskTimeout
+	}
+	cfg.Retry = cfg.Retry.withDefaults()
+
+	tasksCtx, cancelTasks := context.WithCancel(context.Background())
 
 	return &Consumer{
-		conn:           conn,
-		channel:        ch,
+		broker:         broker,
 		imageProcessor: imageProcessor,
 		productRepo:    productRepo,
 		productService: productService,
-		queueName:      "image_processing",
-		dlqName:        "image_processing_dlq",
+		events:         events,
+		cfg:            cfg,
+		queueName:      messaging.ImageProcessingQueue,
+		dlqName:        messaging.ImageProcessingDLQ,
+		consumerTag:    "image-processor-" + uuid.New().String(),
+		stopping:       make(chan struct{}),
+		tasksCtx:       tasksCtx,
+		cancelTasks:    cancelTasks,
 	}, nil
 }
 
+// Start consumes the task queue, which the broker declares as part of
+// Topology, and keeps consuming it across reconnects.
 func (c *Consumer) Start() error {
-	// Declare main queue
-	q, err := c.channel.QueueDeclare(
-		c.queueName,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
-	if err != nil {
-		return err
+	// Let the broker hand out one unacknowledged task per worker
+	c.subscription = c.broker.Subscribe(c.queueName, c.consumerTag, c.cfg.Workers)
+
+	metrics.Add(metricWorkers, int64(c.cfg.Workers))
+	for i := 0; i < c.cfg.Workers; i++ {
+		c.workers.Add(1)
+		go func() {
+			defer c.workers.Done()
+			for d := range c.subscription.Deliveries() {
+				c.handleDelivery(d)
+			}
+		}()
 	}
 
-	// Declare DLQ
-	_, err = c.channel.QueueDeclare(
-		c.dlqName,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
+	return nil
+}
 
-	if err != nil {
-		return err
+// Shutdown stops consuming and waits for in-flight tasks. Tasks still running
+// when ctx expires are cancelled and requeued, and prefetched deliveries that
+// were never started go straight back to the queue.
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	close(c.stopping)
+	if err := c.subscription.Cancel(); err != nil {
+		log.Printf("Failed to cancel consumer: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// Cancelled tasks requeue themselves quickly, wait for them to do so
+		c.cancelTasks()
+		<-done
+		return ctx.Err()
 	}
+}
+
+func (c *Consumer) shuttingDown() bool {
+	select {
+	case <-c.stopping:
+		return true
+	default:
+		return false
+	}
+}
 
-	// Set QoS
-	err = c.channel.Qos(
-		1,     // prefetch count
-		0,     // prefetch size
-		false, // global
-	)
+func (c *Consumer) handleDelivery(d amqp.Delivery) {
+	if c.shuttingDown() {
+		d.Nack(false, true)
+		return
+	}
+
+	metrics.Add(metricTasksInFlight, 1)
+	defer metrics.Add(metricTasksInFlight, -1)
+
+	envelope, task, err := messaging.DecodeImageProcessingTask(d.Body)
 	if err != nil {
-		return err
+		c.fail(d, task, err, errorClass(err))
+		return
 	}
+	// Tasks split off this one for retries share its correlation ID
+	correlationID := envelope.Correlation()
 
-	msgs, err := c.channel.Consume(
-		q.Name, // queue
-		"",     // consumer
-		false,  // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
-	)
+	ctx, cancel := context.WithTimeout(c.tasksCtx, c.cfg.TaskTimeout)
+	defer cancel()
+
+	_, err = c.markImages(task, models.ImageResult{Status: models.ImageStatusProcessing})
 	if err != nil {
-		return err
+		if !c.dropIfDeleted(d, task, err) {
+			c.fail(d, task, err, messaging.ErrorClassProcessing)
+		}
+		return
 	}
 
-	go func() {
-		for d := range msgs {
-			var task ImageProcessingTask
-			if err := json.Unmarshal(d.Body, &task); err != nil {
-				c.handleProcessingError(task, err)
-				d.Nack(false, true)
-				continue
-			}
+	outcomes := c.processImages(ctx, task.Images)
+	if c.tasksCtx.Err() != nil {
+		log.Printf("Requeueing task for product %d interrupted by shutdown", task.ProductID)
+		c.requeue(d, task, correlationID, outcomes)
+		return
+	}
+	c.finish(d, task, correlationID, outcomes)
+}
 
-			err = c.productRepo.UpdateProcessingStatus(task.ProductID, "processing")
-			if err != nil {
-				c.handleProcessingError(task, err)
-				d.Nack(false, true)
-				continue
-			}
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, messaging.ErrUnsupportedVersion):
+		return messaging.ErrorClassUnsupportedVersion
+	case errors.Is(err, messaging.ErrInvalidMessage):
+		return messaging.ErrorClassInvalidTask
+	case errors.Is(err, processor.ErrInvalidImage):
+		return messaging.ErrorClassInvalidImage
+	case errors.Is(err, context.DeadlineExceeded):
+		return messaging.ErrorClassTimeout
+	default:
+		return messaging.ErrorClassProcessing
+	}
+}
 
-			var compressedURLs pq.StringArray
-			var processingError error
-
-			for _, url := range task.Images {
-				for retries := 0; retries < 3; retries++ {
-					compressedURL, err := c.imageProcessor.ProcessImage(url)
-					if err == nil {
-						compressedURLs = append(compressedURLs, compressedURL)
-						break
-					}
-					if retries == 2 {
-						processingError = err
-					}
-					time.Sleep(time.Second * time.Duration(retries+1))
-				}
-				if processingError != nil {
-					break
-				}
-			}
+// retryable reports whether failures of the class may succeed on another
+// attempt.
+func retryable(class string) bool {
+	return class != messaging.ErrorClassInvalidTask &&
+		class != messaging.ErrorClassUnsupportedVersion &&
+		class != messaging.ErrorClassInvalidImage
+}
 
-			if processingError != nil {
-				c.handleProcessingError(task, processingError)
-				d.Nack(false, false)
-				continue
-			}
+// imageOutcome is the result of processing one image of a task.
+type imageOutcome struct {
+	source string
+	image  models.ProcessedImage
+	err    error
+}
+
+// processImages processes up to ImageConcurrency images at once, keeping the
+// outcomes in the order of urls. Images fail independently of each other.
+func (c *Consumer) processImages(ctx context.Context, urls []string) []imageOutcome {
+	outcomes := make([]imageOutcome, len(urls))
 
-			// Ensure you're passing pq.Array here
-			err = c.productRepo.UpdateCompressedImages(task.ProductID, compressedURLs)
+	var g errgroup.Group
+	g.SetLimit(c.cfg.ImageConcurrency)
 
+	for i, url := range urls {
+		g.Go(func() error {
+			metrics.Add(metricImagesInFlight, 1)
+			defer metrics.Add(metricImagesInFlight, -1)
+
+			image, err := c.imageProcessor.ProcessImage(ctx, url)
 			if err != nil {
-				c.handleProcessingError(task, err)
-				d.Nack(false, true)
-				continue
+				metrics.Add(metricImagesFailed, 1)
+			} else {
+				metrics.Add(metricImagesProcessed, 1)
 			}
+			outcomes[i] = imageOutcome{source: url, image: image, err: err}
+			return nil
+		})
+	}
 
-			// Invalidate cache after updating images
-			if err := c.productService.InvalidateCache(task.ProductID); err != nil {
-				log.Printf("Failed to invalidate cache: %v", err)
-			}
+	g.Wait()
+	return outcomes
+}
 
-			err = c.productRepo.UpdateProcessingStatus(task.ProductID, "completed")
-			if err != nil {
-				log.Printf("Failed to update status to completed: %v", err)
+// finish records the outcome of every image of the task, keeping the images
+// that were processed even when others failed. Failed images are handed on
+// as a task of their own: to a retry queue while they may still succeed and
+// have attempts left, otherwise to the dead letter queue. The task is only
+// requeued when neither could be handed on.
+func (c *Consumer) finish(d amqp.Delivery, task messaging.ImageProcessingTask, correlationID string, outcomes []imageOutcome) {
+	attempts := failures(d.Headers) + 1
+	delay, canRetry := c.cfg.Retry.NextDelay(attempts)
+
+	var results []models.ImageResult
+	var retry, dead []imageOutcome
+	for _, o := range outcomes {
+		if o.err == nil {
+			results = append(results, models.ImageResult{
+				Source:    o.source,
+				Status:    models.ImageStatusDone,
+				Variants:  o.image.Variants,
+				Attempted: true,
+			})
+			continue
+		}
+
+		status := models.ImageStatusFailed
+		if canRetry && retryable(errorClass(o.err)) {
+			status = models.ImageStatusPending
+			retry = append(retry, o)
+		} else {
+			dead = append(dead, o)
+		}
+		results = append(results, models.ImageResult{
+			Source:    o.source,
+			Status:    status,
+			Error:     o.err.Error(),
+			Attempted: true,
+		})
+	}
+
+	product, err := c.saveResults(task.ProductID, results)
+	if err != nil {
+		if !c.dropIfDeleted(d, task, err) {
+			c.fail(d, task, err, messaging.ErrorClassProcessing)
+		}
+		return
+	}
+
+	// Both parts go out before the task is acknowledged. A part the broker
+	// refuses is dealt with on its own, so images already handed on are never
+	// redelivered with it.
+	var handedOn bool
+	var stranded []imageOutcome
+	if len(retry) > 0 {
+		log.Printf("Attempt %d at %d images of product %d failed, retrying in %s: %v",
+			attempts, len(retry), task.ProductID, delay, retry[0].err)
+		if err := c.handOn(d, task.ProductID, correlationID, messaging.RetryQueueName(c.queueName, delay), retry); err != nil {
+			log.Printf("Failed to schedule retry for product %d: %v", task.ProductID, err)
+			stranded = retry
+		} else {
+			handedOn = true
+		}
+	}
+	deadLettered := true
+	if len(dead) > 0 {
+		log.Printf("Failed to process %d images of product %d after %d attempts: %v",
+			len(dead), task.ProductID, attempts, dead[0].err)
+		if err := c.handOn(d, task.ProductID, correlationID, c.dlqName, dead); err != nil {
+			log.Printf("Failed to dead-letter images of product %d: %v", task.ProductID, err)
+			deadLettered = false
+		} else {
+			handedOn = true
+		}
+	}
+
+	if (len(stranded) > 0 || !deadLettered) && !handedOn {
+		// Nothing went out, so the redelivered task repeats no handed on image
+		d.Nack(false, true)
+		return
+	}
+	if len(stranded) > 0 {
+		// The retry can't be scheduled without redelivering the images that
+		// were dead-lettered, so these fail here instead of staying pending
+		results := make([]models.ImageResult, len(stranded))
+		for i, o := range stranded {
+			results[i] = models.ImageResult{
+				Source:    o.source,
+				Status:    models.ImageStatusFailed,
+				Error:     o.err.Error(),
+				Attempted: true,
 			}
+		}
+		if updated, err := c.saveResults(task.ProductID, results); err != nil {
+			log.Printf("Failed to save results of product %d: %v", task.ProductID, err)
+		} else {
+			product = updated
+		}
+	}
 
-			d.Ack(false)
+	if len(retry)+len(dead) > 0 {
+		metrics.Add(metricTasksFailed, 1)
+	} else {
+		metrics.Add(metricTasksCompleted, 1)
+	}
+	d.Ack(false)
+	c.notifyProcessed(product)
+}
+
+// requeue keeps the images of a task interrupted by shutdown that were
+// already done and hands the rest back to the main queue, without counting
+// the interrupted attempt.
+func (c *Consumer) requeue(d amqp.Delivery, task messaging.ImageProcessingTask, correlationID string, outcomes []imageOutcome) {
+	var results []models.ImageResult
+	unfinished := messaging.ImageProcessingTask{ProductID: task.ProductID}
+	for _, o := range outcomes {
+		if o.err == nil {
+			results = append(results, models.ImageResult{
+				Source:    o.source,
+				Status:    models.ImageStatusDone,
+				Variants:  o.image.Variants,
+				Attempted: true,
+			})
+			continue
 		}
-	}()
+		unfinished.Images = append(unfinished.Images, o.source)
+		results = append(results, models.ImageResult{Source: o.source, Status: models.ImageStatusPending})
+	}
 
-	return nil
+	if _, err := c.saveResults(task.ProductID, results); err != nil {
+		if c.dropIfDeleted(d, task, err) {
+			return
+		}
+		log.Printf("Failed to save results of product %d: %v", task.ProductID, err)
+		d.Nack(false, true)
+		return
+	}
+
+	if len(unfinished.Images) > 0 {
+		body, err := messaging.EncodeImageProcessingTask(unfinished, correlationID)
+		if err == nil {
+			err = c.publish(d, c.queueName, d.Headers, body)
+		}
+		if err != nil {
+			log.Printf("Failed to requeue images of product %d: %v", task.ProductID, err)
+			d.Nack(false, true)
+			return
+		}
+	}
+	d.Ack(false)
 }
 
-func (c *Consumer) handleProcessingError(task ImageProcessingTask, err error) {
-	log.Printf("Error processing task for product %d: %v", task.ProductID, err)
+// fail schedules another attempt at the whole task after the retry delay,
+// or moves it to the dead letter queue once it is out of attempts or can
+// never succeed. Either way the original body is republished with the
+// failure recorded in its headers.
+func (c *Consumer) fail(d amqp.Delivery, task messaging.ImageProcessingTask, err error, class string) {
+	metrics.Add(metricTasksFailed, 1)
+	headers := failureHeaders(d, c.queueName, err, class)
+	attempts := failures(headers)
 
-	// Update product status to failed
-	if err := c.productRepo.UpdateProcessingStatus(task.ProductID, "failed"); err != nil {
-		log.Printf("Failed to update status to failed: %v", err)
+	if delay, ok := c.cfg.Retry.NextDelay(attempts); ok && retryable(class) {
+		log.Printf("Attempt %d for product %d failed, retrying in %s: %v", attempts, task.ProductID, delay, err)
+		if c.republish(d, messaging.RetryQueueName(c.queueName, delay), headers) {
+			_, err := c.markImages(task, models.ImageResult{
+				Status:    models.ImageStatusPending,
+				Error:     err.Error(),
+				Attempted: true,
+			})
+			c.logMarkError(task, err)
+		}
+		return
 	}
 
-	// Send to dead letter queue
-	errMsg, _ := json.Marshal(map[string]interface{}{
-		"product_id": task.ProductID,
-		"error":      err.Error(),
-		"timestamp":  time.Now(),
-	})
+	log.Printf("Error processing task for product %d after %d attempts: %v", task.ProductID, attempts, err)
+	if c.republish(d, c.dlqName, headers) && task.ProductID != 0 {
+		product, err := c.markImages(task, models.ImageResult{
+			Status:    models.ImageStatusFailed,
+			Error:     err.Error(),
+			Attempted: true,
+		})
+		c.logMarkError(task, err)
+		if err == nil {
+			c.notifyProcessed(product)
+		}
+	}
+}
 
-	err = c.channel.Publish(
-		"",        // exchange
-		c.dlqName, // routing key
-		false,     // mandatory
-		false,     // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        errMsg,
-		},
-	)
+// dropIfDeleted acknowledges the task when err means its product was
+// deleted, which no retry can fix, and reports whether it did.
+func (c *Consumer) dropIfDeleted(d amqp.Delivery, task messaging.ImageProcessingTask, err error) bool {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	log.Printf("Dropping task for product %d, which no longer exists", task.ProductID)
+	metrics.Add(metricTasksDropped, 1)
+	d.Ack(false)
+	return true
+}
+
+// notifyProcessed tells subscribers once the product has no images left to
+// process.
+func (c *Consumer) notifyProcessed(product *models.Product) {
+	if models.ProcessingDone(product.ProcessingStatus) {
+		c.productService.ProductProcessed(product)
+	}
+}
+
+func (c *Consumer) logMarkError(task messaging.ImageProcessingTask, err error) {
 	if err != nil {
-		log.Printf("Failed to publish to DLQ: %v", err)
+		log.Printf("Failed to update image status of product %d: %v", task.ProductID, err)
+	}
+}
+
+// markImages records the same result for every image of the task.
+func (c *Consumer) markImages(task messaging.ImageProcessingTask, result models.ImageResult) (*models.Product, error) {
+	results := make([]models.ImageResult, len(task.Images))
+	for i, source := range task.Images {
+		results[i] = result
+		results[i].Source = source
 	}
+	return c.saveResults(task.ProductID, results)
 }
 
-func (c *Consumer) Close() error {
-	if err := c.channel.Close(); err != nil {
+// saveResults merges image results into the product, which also updates its
+// processing status, drops the cached copies of the product and its owner's
+// listings, and announces the new status. The event comes last so that
+// readers reloading the product on it never see a stale cached copy.
+func (c *Consumer) saveResults(productID uint, results []models.ImageResult) (*models.Product, error) {
+	product, err := c.productRepo.MergeImageResults(productID, results)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.productService.InvalidateCache(product); err != nil {
+		log.Printf("Failed to invalidate cache: %v", err)
+	}
+	if err := c.events.PublishProcessingStatus(product); err != nil {
+		log.Printf("Failed to publish status of product %d: %v", productID, err)
+	}
+	return product, nil
+}
+
+// handOn publishes failed images to queue, trying again after a pause while
+// the broker refuses them, up to handOnAttempts times or until shutdown.
+func (c *Consumer) handOn(d amqp.Delivery, productID uint, correlationID, queue string, failed []imageOutcome) error {
+	delay := handOnDelay
+	for attempt := 1; ; attempt++ {
+		err := c.publishFailures(d, productID, correlationID, queue, failed)
+		if err == nil || attempt == handOnAttempts {
+			return err
+		}
+		log.Printf("Publishing images of product %d to %s failed, trying again in %s: %v", productID, queue, delay, err)
+		select {
+		case <-c.tasksCtx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// publishFailures sends failed images on to queue as a task of their own,
+// with the first failure recorded in its headers.
+func (c *Consumer) publishFailures(d amqp.Delivery, productID uint, correlationID, queue string, failed []imageOutcome) error {
+	task := messaging.ImageProcessingTask{ProductID: productID}
+	for _, o := range failed {
+		task.Images = append(task.Images, o.source)
+	}
+	body, err := messaging.EncodeImageProcessingTask(task, correlationID)
+	if err != nil {
 		return err
 	}
-	return c.conn.Close()
+
+	headers := failureHeaders(d, c.queueName, failed[0].err, errorClass(failed[0].err))
+	return c.publish(d, queue, headers, body)
+}
+
+// republish sends the delivery's body to queue and acknowledges the
+// delivery. When publishing fails the delivery is requeued instead so the
+// task isn't lost.
+func (c *Consumer) republish(d amqp.Delivery, queue string, headers amqp.Table) bool {
+	if err := c.publish(d, queue, headers, d.Body); err != nil {
+		log.Printf("Failed to publish to %s: %v", queue, err)
+		d.Nack(false, true)
+		return false
+	}
+
+	d.Ack(false)
+	return true
+}
+
+// publish sends body to queue on behalf of the delivery and waits for the
+// broker to confirm it. Messages get an ID on their first failure, which
+// they keep through retries and on the dead letter queue.
+func (c *Consumer) publish(d amqp.Delivery, queue string, headers amqp.Table, body []byte) error {
+	messageID := d.MessageId
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
+	return c.broker.PublishMessage(context.Background(), queue, amqp.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Headers:      headers,
+		Body:         body,
+	})
 }
@@ -0,0 +1,18 @@
+package queue
+
+import "expvar"
+
+// metrics exposes the consumer's in-flight and completed work under the
+// "image_processor" expvar.
+var metrics = expvar.NewMap("image_processor")
+
+const (
+	metricWorkers         = "workers"
+	metricTasksInFlight   = "tasks_in_flight"
+	metricImagesInFlight  = "images_in_flight"
+	metricTasksCompleted  = "tasks_completed"
+	metricTasksFailed     = "tasks_failed"
+	metricTasksDropped    = "tasks_dropped"
+	metricImagesProcessed = "images_processed"
+	metricImagesFailed    = "images_failed"
+)
@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KPVISHNUSAI/product-management-system/pkg/messaging"
+	"github.com/streadway/amqp"
+)
+
+// RetryPolicy decides when a failed task is tried again. Attempt n is
+// delayed by Delays[n-1], or by the last delay once they run out, until
+// MaxAttempts attempts have failed and the task is dead-lettered.
+type RetryPolicy struct {
+	Delays      []time.Duration
+	MaxAttempts int
+}
+
+var defaultRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute}
+
+// ParseRetryDelays parses a comma separated list of durations, e.g.
+// "5s,30s,2m".
+func ParseRetryDelays(spec string) ([]time.Duration, error) {
+	var delays []time.Duration
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		delay, err := time.ParseDuration(entry)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("invalid retry delay %q", entry)
+		}
+		delays = append(delays, delay)
+	}
+	return delays, nil
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if len(p.Delays) == 0 {
+		p.Delays = defaultRetryDelays
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = len(p.Delays) + 1
+	}
+	return p
+}
+
+// NextDelay returns the wait before the attempt after the given number of
+// failures, or false when no attempts are left. Fewer than one failure
+// counts as one.
+func (p RetryPolicy) NextDelay(failures int) (time.Duration, bool) {
+	p = p.withDefaults()
+	if failures >= p.MaxAttempts {
+		return 0, false
+	}
+	return p.Delays[min(max(failures, 1), len(p.Delays))-1], true
+}
+
+// Topology returns the queues the image processor uses with the policy: the
+// task queue, the dead letter queue and a delay queue per retry delay.
+func Topology(policy RetryPolicy) []messaging.Queue {
+	return messaging.ImageProcessingTopology(policy.withDefaults().Delays)
+}
+
+// failures reads the number of failed attempts recorded on a delivery.
+func failures(headers amqp.Table) int {
+	switch n := headers[messaging.HeaderAttempts].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
+
+// failureHeaders copies the delivery's headers and records one more failure.
+func failureHeaders(d amqp.Delivery, queue string, err error, class string) amqp.Table {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, ok := headers[messaging.HeaderFirstFailedAt]; !ok {
+		headers[messaging.HeaderFirstFailedAt] = now
+	}
+	headers[messaging.HeaderAttempts] = int32(failures(d.Headers) + 1)
+	headers[messaging.HeaderError] = err.Error()
+	headers[messaging.HeaderErrorClass] = class
+	headers[messaging.HeaderFailedAt] = now
+	headers[messaging.HeaderOriginalQueue] = queue
+	return headers
+}
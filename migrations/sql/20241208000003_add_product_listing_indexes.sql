@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE INDEX idx_products_user_price_id ON app_products(user_id, product_price, id);
+CREATE INDEX idx_products_user_created_id ON app_products(user_id, created_at, id);
+CREATE INDEX idx_products_user_name_id ON app_products(user_id, product_name, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_user_name_id;
+DROP INDEX IF EXISTS idx_products_user_created_id;
+DROP INDEX IF EXISTS idx_products_user_price_id;
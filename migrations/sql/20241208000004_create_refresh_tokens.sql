@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE app_refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES app_users(id) ON DELETE CASCADE,
+    family_id VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON app_refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON app_refresh_tokens(family_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS app_refresh_tokens;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE app_users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'editor'
+    CONSTRAINT chk_users_role CHECK (role IN ('admin', 'editor', 'viewer'));
+
+-- +goose Down
+ALTER TABLE app_users DROP COLUMN IF EXISTS role;
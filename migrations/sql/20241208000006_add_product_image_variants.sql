@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE app_products ADD COLUMN image_variants JSONB NOT NULL DEFAULT '[]';
+
+-- Keep existing compressed images as a single "compressed" variant of the
+-- source image at the same position.
+UPDATE app_products p
+SET image_variants = COALESCE((
+    SELECT jsonb_agg(jsonb_build_object(
+        'source', COALESCE(p.product_images[c.position], ''),
+        'variants', jsonb_build_array(jsonb_build_object('name', 'compressed', 'url', c.url))
+    ) ORDER BY c.position)
+    FROM unnest(p.compressed_product_images) WITH ORDINALITY AS c(url, position)
+), '[]')
+WHERE compressed_product_images IS NOT NULL;
+
+ALTER TABLE app_products DROP COLUMN compressed_product_images;
+
+-- +goose Down
+ALTER TABLE app_products ADD COLUMN compressed_product_images TEXT[];
+
+UPDATE app_products p
+SET compressed_product_images = ARRAY(
+    SELECT image -> 'variants' -> 0 ->> 'url'
+    FROM jsonb_array_elements(p.image_variants) WITH ORDINALITY AS i(image, position)
+    ORDER BY i.position
+);
+
+ALTER TABLE app_products DROP COLUMN IF EXISTS image_variants;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Images of products processed before per-image status existed share the
+-- product's outcome; images of products still being processed are pending.
+UPDATE app_products p
+SET image_variants = COALESCE((
+    SELECT jsonb_agg(i.image || jsonb_build_object(
+        'status', CASE p.processing_status
+            WHEN 'completed' THEN 'done'
+            WHEN 'failed' THEN 'failed'
+            ELSE 'pending'
+        END,
+        'attempts', 0
+    ) ORDER BY i.position)
+    FROM jsonb_array_elements(p.image_variants) WITH ORDINALITY AS i(image, position)
+), '[]');
+
+-- +goose Down
+UPDATE app_products p
+SET image_variants = COALESCE((
+    SELECT jsonb_agg(i.image - 'status' - 'error' - 'attempts' ORDER BY i.position)
+    FROM jsonb_array_elements(p.image_variants) WITH ORDINALITY AS i(image, position)
+), '[]');
+
+UPDATE app_products
+SET processing_status = 'completed'
+WHERE processing_status = 'partially_completed';
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE app_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    queue TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The relay polls unsent messages, cleanup removes old sent ones
+CREATE INDEX idx_outbox_pending ON app_outbox(next_attempt_at) WHERE sent_at IS NULL;
+CREATE INDEX idx_outbox_sent_at ON app_outbox(sent_at) WHERE sent_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS app_outbox;
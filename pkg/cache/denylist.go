@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+const denylistPrefix = "denylist:jti:"
+
+// TokenDenylist records revoked access token IDs (jti) until the tokens
+// would have expired anyway.
+type TokenDenylist struct {
+	cache *RedisCache
+}
+
+func NewTokenDenylist(cache *RedisCache) *TokenDenylist {
+	return &TokenDenylist{cache: cache}
+}
+
+func (d *TokenDenylist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return d.cache.client.Set(ctx, denylistPrefix+jti, 1, ttl).Err()
+}
+
+func (d *TokenDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.cache.client.Exists(ctx, denylistPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
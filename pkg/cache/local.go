@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const defaultLocalSize = 10000
+
+// LocalCache is a size-bounded in-process cache. It evicts the least
+// recently used entry when full and stops returning entries once their TTL
+// has passed. Values are kept encoded so callers never share decoded
+// objects.
+type LocalCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// order holds the entries, most recently used first.
+	order *list.List
+}
+
+type localEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+// NewLocalCache creates a cache holding at most size entries, or 10000 when
+// size isn't positive.
+func NewLocalCache(size int) *LocalCache {
+	if size <= 0 {
+		size = defaultLocalSize
+	}
+	return &LocalCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the data stored under key unless it has expired.
+func (c *LocalCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*localEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.data, true
+}
+
+// Set stores data under key for ttl, or until evicted when ttl isn't
+// positive.
+func (c *LocalCache) Set(key string, data []byte, ttl time.Duration) {
+	entry := &localEntry{key: key, data: data}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		metrics.Add(metricLocalEvictions, 1)
+	}
+}
+
+// Delete removes keys.
+func (c *LocalCache) Delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if e, ok := c.entries[key]; ok {
+			c.remove(e)
+		}
+	}
+}
+
+// Purge removes every entry.
+func (c *LocalCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// Len returns the number of entries, including expired ones not yet
+// removed.
+func (c *LocalCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LocalCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*localEntry).key)
+}
@@ -0,0 +1,17 @@
+package cache
+
+import "expvar"
+
+// metrics exposes hits and misses of each TieredCache tier under the
+// "cache" expvar.
+var metrics = expvar.NewMap("cache")
+
+const (
+	metricLocalHits      = "local_hits"
+	metricLocalMisses    = "local_misses"
+	metricLocalEvictions = "local_evictions"
+	metricRedisHits      = "redis_hits"
+	metricRedisMisses    = "redis_misses"
+	metricInvalidations  = "invalidations_received"
+	metricLocalPurges    = "local_purges"
+)
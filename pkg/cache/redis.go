@@ -2,37 +2,143 @@ package cache
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// RedisCache works against a single node, a Sentinel-managed master or a
+// cluster. Multi-key operations issue one command per key, pipelined, so
+// they never span hash slots.
 type RedisCache struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
+// Redis deployment modes.
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// ErrInvalidRedisConfig is returned for a RedisConfig that can't describe
+// a deployment.
+var ErrInvalidRedisConfig = errors.New("invalid redis config")
+
+// ErrMiss is returned by Get for a key that isn't cached.
+var ErrMiss = redis.Nil
+
+// RedisConfig selects the Redis deployment and tunes the connections to
+// it. Zero values fall back to go-redis defaults.
 type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
+	// Mode is ModeStandalone (the default), ModeSentinel or ModeCluster.
+	Mode string
+	// Addrs are host:port addresses of the node, of the sentinels, or of
+	// any cluster nodes to discover the rest from.
+	Addrs []string
+	// MasterName is the master monitored by the sentinels.
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	// DB selects the database. Clusters only have DB 0.
+	DB int
+	// TLS connects over TLS, verifying servers against the system roots.
+	TLS bool
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
 }
 
-func NewRedisCache(addr string, password string) (*RedisCache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr, // format should be "host:port"
-		Password: password,
-		DB:       0,
-	})
+// NewRedisCache connects to the deployment described by cfg and checks it
+// answers.
+func NewRedisCache(cfg RedisConfig) (*RedisCache, error) {
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, err
 	}
 
 	return &RedisCache{client: client}, nil
 }
 
+func newRedisClient(cfg RedisConfig) (redis.UniversalClient, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, fmt.Errorf("%w: no addresses", ErrInvalidRedisConfig)
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLS {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	switch cfg.Mode {
+	case "", ModeStandalone:
+		if len(cfg.Addrs) > 1 {
+			return nil, fmt.Errorf("%w: a standalone node has a single address", ErrInvalidRedisConfig)
+		}
+		return redis.NewClient(&redis.Options{
+			Addr:         cfg.Addrs[0],
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+		}), nil
+	case ModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("%w: sentinel mode needs a master name", ErrInvalidRedisConfig)
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         cfg.PoolSize,
+			MinIdleConns:     cfg.MinIdleConns,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+		}), nil
+	case ModeCluster:
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("%w: clusters only have DB 0", ErrInvalidRedisConfig)
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.Addrs,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+		}), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidRedisConfig, cfg.Mode)
+	}
+}
+
 func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	data, err := json.Marshal(value)
 	if err != nil {
@@ -49,10 +155,50 @@ func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) erro
 	return json.Unmarshal(data, dest)
 }
 
+// BatchGet returns the raw values of the keys that exist, read in one
+// pipeline.
 func (c *RedisCache) BatchGet(ctx context.Context, keys []string) (map[string]interface{}, error) {
-	pipe := c.client.Pipeline()
-	cmds := make(map[string]*redis.StringCmd)
+	found, err := c.getMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(found))
+	for key, data := range found {
+		result[key] = string(data)
+	}
+	return result, nil
+}
 
+// BatchGetInto decodes the entry under each key of dests into the pointer
+// it maps to, reading every key in one pipeline. It returns the keys that
+// weren't found or couldn't be decoded.
+func (c *RedisCache) BatchGetInto(ctx context.Context, dests map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(dests))
+	for key := range dests {
+		keys = append(keys, key)
+	}
+
+	found, err := c.getMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, key := range keys {
+		data, ok := found[key]
+		if !ok || json.Unmarshal(data, dests[key]) != nil {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
+
+// getMany returns the raw values of the keys that exist. Every key is read
+// with its own GET so the pipeline may span cluster hash slots.
+func (c *RedisCache) getMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	pipe := c.client.Pipeline()
+	cmds := make(map[string]*redis.StringCmd, len(keys))
 	for _, key := range keys {
 		cmds[key] = pipe.Get(ctx, key)
 	}
@@ -62,17 +208,102 @@ func (c *RedisCache) BatchGet(ctx context.Context, keys []string) (map[string]in
 		return nil, err
 	}
 
-	result := make(map[string]interface{})
+	found := make(map[string][]byte, len(keys))
 	for key, cmd := range cmds {
-		data, err := cmd.Result()
-		if err == nil {
-			result[key] = data
+		if data, err := cmd.Bytes(); err == nil {
+			found[key] = data
 		}
 	}
-
-	return result, nil
+	return found, nil
 }
 
 func (c *RedisCache) Delete(ctx context.Context, key string) error {
 	return c.client.Del(ctx, key).Err()
 }
+
+// tagPrefix namespaces the sets that track which keys carry a tag.
+const tagPrefix = "tag:"
+
+// tagScript adds ARGV[1] to the tag set KEYS[1] and makes the set live at
+// least ARGV[2] milliseconds, or forever when that isn't positive, so it
+// never expires before a key it tracks.
+var tagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	redis.call('PERSIST', KEYS[1])
+	return 1
+end
+local current = redis.call('PTTL', KEYS[1])
+if existed == 0 or (current >= 0 and current < ttl) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// SetWithTags stores value like Set and records key under each tag, so that
+// InvalidateTags can drop it along with everything else sharing a tag. The
+// tags are recorded first: a value is never cached without them.
+func (c *RedisCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) > 0 {
+		pipe := c.client.Pipeline()
+		for _, tag := range tags {
+			tagScript.Eval(ctx, pipe, []string{tagPrefix + tag}, key, expiration.Milliseconds())
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return c.client.Set(ctx, key, data, expiration).Err()
+}
+
+// InvalidateTags deletes every key tagged with any of tags. Keys are popped
+// from the tag sets in batches, so keys tagged while this runs are either
+// deleted or stay tracked for the next invalidation.
+func (c *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	_, err := c.invalidateTags(ctx, tags...)
+	return err
+}
+
+// invalidateTags is InvalidateTags returning the keys it popped from the tag
+// sets, including when it fails part way.
+func (c *RedisCache) invalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	var popped []string
+	for _, tag := range tags {
+		for {
+			keys, err := c.client.SPopN(ctx, tagPrefix+tag, 100).Result()
+			if err != nil {
+				return popped, err
+			}
+			if len(keys) == 0 {
+				break
+			}
+			popped = append(popped, keys...)
+			if err := c.deleteKeys(ctx, keys); err != nil {
+				return popped, err
+			}
+		}
+	}
+	return popped, nil
+}
+
+// deleteKeys deletes keys one command each, which a cluster may route to
+// different nodes.
+func (c *RedisCache) deleteKeys(ctx context.Context, keys []string) error {
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// StreamEntry is one entry of an event stream. IDs increase with every
+// entry, so a reader can resume after the last ID it has seen.
+type StreamEntry struct {
+	ID   string
+	Data []byte
+}
+
+// StreamConfig bounds the history kept per stream. Zero values fall back to
+// the defaults below.
+type StreamConfig struct {
+	// MaxLen is the approximate number of entries kept per stream.
+	MaxLen int64
+	// TTL removes streams that haven't been written to for this long.
+	TTL time.Duration
+	// Readers, when set, serves the blocking reads, so waiting readers
+	// can't take every connection from the client passed to
+	// NewEventStream.
+	Readers *RedisCache
+	// Block is the longest a single read waits in Redis. Longer waits are
+	// split into several reads, so a reader whose context is cancelled
+	// gives its connection back within Block.
+	Block time.Duration
+}
+
+const (
+	defaultStreamMaxLen = 100
+	defaultStreamTTL    = 24 * time.Hour
+	defaultStreamBlock  = time.Second
+
+	streamPrefix    = "stream:"
+	streamDataField = "data"
+)
+
+// EventStream stores events in Redis streams so readers can wait for new
+// events and resume where they left off.
+type EventStream struct {
+	client  redis.UniversalClient
+	readers redis.UniversalClient
+	cfg     StreamConfig
+}
+
+func NewEventStream(cache *RedisCache, cfg StreamConfig) *EventStream {
+	if cfg.MaxLen <= 0 {
+		cfg.MaxLen = defaultStreamMaxLen
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultStreamTTL
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = defaultStreamBlock
+	}
+	readers := cache.client
+	if cfg.Readers != nil {
+		readers = cfg.Readers.client
+	}
+	return &EventStream{client: cache.client, readers: readers, cfg: cfg}
+}
+
+// Publish appends data to stream and returns the ID of the new entry.
+func (s *EventStream) Publish(ctx context.Context, stream string, data []byte) (string, error) {
+	key := streamPrefix + stream
+
+	pipe := s.client.TxPipeline()
+	add := pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: s.cfg.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{streamDataField: data},
+	})
+	pipe.Expire(ctx, key, s.cfg.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return add.Val(), nil
+}
+
+// Last returns the ID of the newest entry of stream, or "0" when the stream
+// is empty, which reads the stream from the start.
+func (s *EventStream) Last(ctx context.Context, stream string) (string, error) {
+	messages, err := s.client.XRevRangeN(ctx, streamPrefix+stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "0", nil
+	}
+	return messages[0].ID, nil
+}
+
+// Read returns the entries of stream after the given ID, waiting up to wait
+// for one to arrive. No entries and no error means the wait timed out.
+// Cancelling ctx ends the wait within the configured Block.
+func (s *EventStream) Read(ctx context.Context, stream, after string, wait time.Duration) ([]StreamEntry, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Redis blocks forever on a zero block, so stop short of it
+		block := min(time.Until(deadline), s.cfg.Block).Truncate(time.Millisecond)
+		if block <= 0 {
+			return nil, nil
+		}
+
+		entries, err := s.read(ctx, stream, after, block)
+		if err != nil || len(entries) > 0 {
+			return entries, err
+		}
+	}
+}
+
+// read is a single XREAD of stream blocking up to block.
+func (s *EventStream) read(ctx context.Context, stream, after string, block time.Duration) ([]StreamEntry, error) {
+	streams, err := s.readers.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{streamPrefix + stream, after},
+		Count:   s.cfg.MaxLen,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []StreamEntry
+	for _, st := range streams {
+		for _, message := range st.Messages {
+			data, _ := message.Values[streamDataField].(string)
+			entries = append(entries, StreamEntry{ID: message.ID, Data: []byte(data)})
+		}
+	}
+	return entries, nil
+}
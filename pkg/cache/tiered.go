@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TieredConfig tunes a TieredCache. Zero values fall back to the defaults
+// below.
+type TieredConfig struct {
+	// Size is the number of entries kept in process.
+	Size int
+	// TTL is how long an entry is served from process memory. It bounds how
+	// stale an entry gets when an invalidation message is lost, and how long
+	// it outlives its Redis copy.
+	TTL time.Duration
+	// Channel is the Redis pub/sub channel carrying invalidations between
+	// instances.
+	Channel string
+}
+
+const (
+	defaultTieredTTL     = 30 * time.Second
+	defaultTieredChannel = "cache:invalidations"
+
+	// resubscribeDelay paces reconnecting to the invalidation channel.
+	resubscribeDelay = time.Second
+)
+
+// TieredCache keeps recently read entries in a LocalCache in front of
+// Redis. Every write, delete and tag invalidation is announced on a pub/sub
+// channel so other instances drop their local copies. While the channel is
+// disconnected, announcements may be missed, so local copies are dropped
+// on reconnecting.
+type TieredCache struct {
+	remote *RedisCache
+	local  *LocalCache
+	cfg    TieredConfig
+
+	// mu orders filling the local tier against invalidations: a value read
+	// from Redis is only kept locally when nothing was invalidated while it
+	// was being read.
+	mu            sync.Mutex
+	invalidations uint64
+
+	pubsub    *redis.PubSub
+	closing   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// invalidation is the message published on the invalidation channel.
+type invalidation struct {
+	Keys []string `json:"keys"`
+}
+
+// NewTieredCache subscribes to cfg.Channel on remote and returns a cache
+// using it. Closing the TieredCache leaves remote open.
+func NewTieredCache(remote *RedisCache, cfg TieredConfig) (*TieredCache, error) {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTieredTTL
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = defaultTieredChannel
+	}
+
+	ctx := context.Background()
+	pubsub := remote.client.Subscribe(ctx, cfg.Channel)
+	// Wait for the subscription so no invalidation is missed from here on
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	c := &TieredCache{
+		remote:  remote,
+		local:   NewLocalCache(cfg.Size),
+		cfg:     cfg,
+		pubsub:  pubsub,
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.listen()
+	return c, nil
+}
+
+// Get decodes the entry under key into dest, reading Redis on a local miss.
+// A miss in both tiers returns ErrMiss.
+func (c *TieredCache) Get(ctx context.Context, key string, dest interface{}) error {
+	if data, ok := c.local.Get(key); ok {
+		metrics.Add(metricLocalHits, 1)
+		return json.Unmarshal(data, dest)
+	}
+	metrics.Add(metricLocalMisses, 1)
+
+	c.mu.Lock()
+	seen := c.invalidations
+	c.mu.Unlock()
+
+	data, err := c.remote.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		metrics.Add(metricRedisMisses, 1)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	metrics.Add(metricRedisHits, 1)
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.invalidations == seen {
+		c.local.Set(key, data, c.cfg.TTL)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// BatchGetInto decodes the entry under each key of dests into the pointer
+// it maps to, reading the keys missing locally from Redis in one pipeline.
+// It returns the keys found in neither tier or that couldn't be decoded.
+func (c *TieredCache) BatchGetInto(ctx context.Context, dests map[string]interface{}) ([]string, error) {
+	var remote []string
+	for key, dest := range dests {
+		data, ok := c.local.Get(key)
+		if ok && json.Unmarshal(data, dest) == nil {
+			metrics.Add(metricLocalHits, 1)
+			continue
+		}
+		metrics.Add(metricLocalMisses, 1)
+		remote = append(remote, key)
+	}
+	if len(remote) == 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	seen := c.invalidations
+	c.mu.Unlock()
+
+	found, err := c.remote.getMany(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	fill := make(map[string][]byte, len(found))
+	for _, key := range remote {
+		data, ok := found[key]
+		if !ok {
+			metrics.Add(metricRedisMisses, 1)
+			missing = append(missing, key)
+			continue
+		}
+		metrics.Add(metricRedisHits, 1)
+		if err := json.Unmarshal(data, dests[key]); err != nil {
+			missing = append(missing, key)
+			continue
+		}
+		fill[key] = data
+	}
+
+	c.mu.Lock()
+	if c.invalidations == seen {
+		for key, data := range fill {
+			c.local.Set(key, data, c.cfg.TTL)
+		}
+	}
+	c.mu.Unlock()
+	return missing, nil
+}
+
+// Set stores value in Redis and drops every local copy of key. The local
+// tier is filled by the next Get.
+func (c *TieredCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// SetWithTags is Set for a tagged entry.
+func (c *TieredCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	if err := c.remote.SetWithTags(ctx, key, value, expiration, tags...); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// Delete removes key from Redis and every local tier.
+func (c *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := c.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// InvalidateTags deletes the entries tagged with any of tags from Redis and
+// every local tier.
+func (c *TieredCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	keys, err := c.remote.invalidateTags(ctx, tags...)
+	if len(keys) > 0 {
+		if perr := c.invalidate(ctx, keys...); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// invalidate drops keys locally and announces them to the other instances.
+func (c *TieredCache) invalidate(ctx context.Context, keys ...string) error {
+	c.evict(keys...)
+
+	data, err := json.Marshal(invalidation{Keys: keys})
+	if err != nil {
+		return err
+	}
+	return c.remote.client.Publish(ctx, c.cfg.Channel, data).Err()
+}
+
+func (c *TieredCache) evict(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidations++
+	c.local.Delete(keys...)
+}
+
+func (c *TieredCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidations++
+	c.local.Purge()
+	metrics.Add(metricLocalPurges, 1)
+}
+
+// listen applies invalidations published by any instance, including this
+// one, until Close.
+func (c *TieredCache) listen() {
+	defer close(c.done)
+
+	ctx := context.Background()
+	for {
+		msg, err := c.pubsub.Receive(ctx)
+		if err != nil {
+			select {
+			case <-c.closing:
+				return
+			default:
+			}
+			// Invalidations published while disconnected are lost
+			log.Printf("Lost the cache invalidation channel, dropping local entries: %v", err)
+			c.purge()
+
+			select {
+			case <-c.closing:
+				return
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// Receive resubscribes after reconnecting; anything published
+			// in between was missed
+			c.purge()
+		case *redis.Message:
+			var inv invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+				log.Printf("Ignoring malformed cache invalidation: %v", err)
+				continue
+			}
+			metrics.Add(metricInvalidations, 1)
+			c.evict(inv.Keys...)
+		}
+	}
+}
+
+// Close stops listening for invalidations. The Redis client stays open.
+func (c *TieredCache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closing)
+		err = c.pubsub.Close()
+		<-c.done
+	})
+	return err
+}
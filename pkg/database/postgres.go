@@ -23,3 +23,12 @@ func NewPostgresDB(host, port, user, password, dbname string) (*gorm.DB, error)
 
 	return db, nil
 }
+
+// Close closes the connection pool behind db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
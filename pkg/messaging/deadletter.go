@@ -0,0 +1,283 @@
+package messaging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// DeadLetter is a task on the dead letter queue together with the failure
+// recorded in its headers.
+type DeadLetter struct {
+	ID            string          `json:"id"`
+	ProductID     uint            `json:"product_id"`
+	Images        []string        `json:"images"`
+	Attempts      int             `json:"attempts"`
+	Error         string          `json:"error"`
+	ErrorClass    string          `json:"error_class"`
+	FirstFailedAt string          `json:"first_failed_at,omitempty"`
+	FailedAt      string          `json:"failed_at,omitempty"`
+	OriginalQueue string          `json:"original_queue,omitempty"`
+	Body          json.RawMessage `json:"body"`
+}
+
+// Replayable reports whether the message carries a task that can run again.
+// Messages dead-lettered by older consumers hold only the error.
+func (m DeadLetter) Replayable() bool {
+	return len(m.Images) > 0
+}
+
+// DeadLetterFilter selects dead letters. Set fields must all match; an
+// empty filter matches everything.
+type DeadLetterFilter struct {
+	IDs        []string
+	ProductID  uint
+	ErrorClass string
+}
+
+func (f DeadLetterFilter) Empty() bool {
+	return len(f.IDs) == 0 && f.ProductID == 0 && f.ErrorClass == ""
+}
+
+func (f DeadLetterFilter) Matches(m DeadLetter) bool {
+	if len(f.IDs) > 0 && !containsString(f.IDs, m.ID) {
+		return false
+	}
+	if f.ProductID != 0 && f.ProductID != m.ProductID {
+		return false
+	}
+	if f.ErrorClass != "" && f.ErrorClass != m.ErrorClass {
+		return false
+	}
+	return true
+}
+
+// DeadLetterQueue inspects and drains a dead letter queue. Messages are
+// read with basic.get and held unacknowledged while the queue is walked, so
+// every message is seen once; the ones left alone return to the queue in
+// their original order when the walk's channel closes.
+type DeadLetterQueue struct {
+	client      *RabbitMQClient
+	queue       string
+	replayQueue string
+	timeout     time.Duration
+}
+
+func NewDeadLetterQueue(client *RabbitMQClient, queue, replayQueue string) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		client:      client,
+		queue:       queue,
+		replayQueue: replayQueue,
+		timeout:     5 * time.Second,
+	}
+}
+
+// List returns up to limit matching messages without removing them. A
+// limit of 0 returns every match.
+func (q *DeadLetterQueue) List(ctx context.Context, filter DeadLetterFilter, limit int) ([]DeadLetter, error) {
+	var matches []DeadLetter
+	err := q.walk(ctx, func(w *deadLetterWalk, d amqp.Delivery, m DeadLetter) (bool, error) {
+		if filter.Matches(m) {
+			matches = append(matches, m)
+		}
+		return limit == 0 || len(matches) < limit, nil
+	})
+	return matches, err
+}
+
+// Purge deletes the matching messages and returns how many were deleted.
+func (q *DeadLetterQueue) Purge(ctx context.Context, filter DeadLetterFilter) (int, error) {
+	purged := 0
+	err := q.walk(ctx, func(w *deadLetterWalk, d amqp.Delivery, m DeadLetter) (bool, error) {
+		if !filter.Matches(m) {
+			return true, nil
+		}
+		if err := d.Ack(false); err != nil {
+			return false, err
+		}
+		purged++
+		return true, nil
+	})
+	return purged, err
+}
+
+// Replay republishes the matching replayable messages to the replay queue
+// with a clean attempt count and removes them from the dead letter queue. A
+// message is only removed once the broker has confirmed its replay.
+func (q *DeadLetterQueue) Replay(ctx context.Context, filter DeadLetterFilter) ([]DeadLetter, error) {
+	var replayed []DeadLetter
+	err := q.walk(ctx, func(w *deadLetterWalk, d amqp.Delivery, m DeadLetter) (bool, error) {
+		if !filter.Matches(m) || !m.Replayable() {
+			return true, nil
+		}
+		if err := w.replay(ctx, d); err != nil {
+			return false, fmt.Errorf("failed to replay message %s: %w", m.ID, err)
+		}
+		if err := d.Ack(false); err != nil {
+			return false, err
+		}
+		replayed = append(replayed, m)
+		return true, nil
+	})
+	return replayed, err
+}
+
+// deadLetterWalk is the channel a walk reads from, which replays publish on
+// in confirm mode.
+type deadLetterWalk struct {
+	queue    *DeadLetterQueue
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// walk calls fn with each message on the queue until fn returns false or the
+// queue is exhausted.
+func (q *DeadLetterQueue) walk(ctx context.Context, fn func(*deadLetterWalk, amqp.Delivery, DeadLetter) (bool, error)) error {
+	conn, err := q.client.connection(ctx)
+	if err != nil {
+		return err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.Confirm(false); err != nil {
+		return err
+	}
+	w := &deadLetterWalk{
+		queue:    q,
+		ch:       ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+	}
+
+	// Bound the walk by the depth at the start so messages dead-lettered
+	// meanwhile don't keep it going
+	state, err := ch.QueueInspect(q.queue)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < state.Messages; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		d, ok, err := ch.Get(q.queue, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		more, err := fn(w, d, parseDeadLetter(d))
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// replay publishes the message's task to the replay queue and waits for the
+// broker to confirm it.
+func (w *deadLetterWalk) replay(ctx context.Context, d amqp.Delivery) error {
+	err := w.ch.Publish(
+		"",                  // exchange
+		w.queue.replayQueue, // routing key
+		false,               // mandatory
+		false,               // immediate
+		amqp.Publishing{
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    d.MessageId,
+			Body:         d.Body,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(w.queue.timeout)
+	defer timer.Stop()
+
+	select {
+	case confirm, ok := <-w.confirms:
+		if !ok || !confirm.Ack {
+			return errors.New("broker rejected the message")
+		}
+		return nil
+	case <-timer.C:
+		return errors.New("timed out waiting for the broker to confirm")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func parseDeadLetter(d amqp.Delivery) DeadLetter {
+	m := DeadLetter{
+		ID:            d.MessageId,
+		Attempts:      headerInt(d.Headers, HeaderAttempts),
+		Error:         headerString(d.Headers, HeaderError),
+		ErrorClass:    headerString(d.Headers, HeaderErrorClass),
+		FirstFailedAt: headerString(d.Headers, HeaderFirstFailedAt),
+		FailedAt:      headerString(d.Headers, HeaderFailedAt),
+		OriginalQueue: headerString(d.Headers, HeaderOriginalQueue),
+		Body:          json.RawMessage(d.Body),
+	}
+
+	// Older consumers dead-lettered {product_id, error, timestamp} with no
+	// headers and no message ID
+	var legacy struct {
+		Error string `json:"error"`
+	}
+	if _, task, err := DecodeImageProcessingTask(d.Body); err == nil {
+		m.ProductID = task.ProductID
+		m.Images = task.Images
+		if json.Unmarshal(d.Body, &legacy) == nil && m.Error == "" {
+			m.Error = legacy.Error
+		}
+	} else if !json.Valid(d.Body) {
+		m.Body, _ = json.Marshal(string(d.Body))
+	}
+	if m.ID == "" {
+		sum := sha256.Sum256(d.Body)
+		m.ID = "sha256-" + hex.EncodeToString(sum[:8])
+	}
+	return m
+}
+
+func headerString(headers amqp.Table, key string) string {
+	value, _ := headers[key].(string)
+	return strings.TrimSpace(value)
+}
+
+func headerInt(headers amqp.Table, key string) int {
+	switch n := headers[key].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message types and the schema version producers write. Consumers accept
+// every version from 1 up to the current one.
+const (
+	TypeImageProcessingTask    = "image_processing_task"
+	ImageProcessingTaskVersion = 1
+)
+
+var (
+	// ErrInvalidMessage is returned for bodies that aren't a valid message.
+	ErrInvalidMessage = errors.New("invalid message")
+	// ErrUnsupportedVersion is returned for messages of a schema version
+	// this build doesn't know, typically written by a newer producer.
+	ErrUnsupportedVersion = errors.New("unsupported message version")
+)
+
+// Envelope wraps every message sent through the broker. CorrelationID ties
+// together the messages that follow from the same original one, such as the
+// retries of a task.
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	ProducedAt    time.Time       `json:"produced_at"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// NewEnvelope wraps payload in an envelope with a new ID.
+func NewEnvelope(msgType string, version int, correlationID string, payload interface{}) (*Envelope, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		ID:            uuid.New().String(),
+		Type:          msgType,
+		Version:       version,
+		CorrelationID: correlationID,
+		ProducedAt:    time.Now().UTC(),
+		Payload:       data,
+	}, nil
+}
+
+// EncodeImageProcessingTask returns the message body for task.
+// correlationID may be empty for a task that doesn't follow from another.
+func EncodeImageProcessingTask(task ImageProcessingTask, correlationID string) ([]byte, error) {
+	envelope, err := NewEnvelope(TypeImageProcessingTask, ImageProcessingTaskVersion, correlationID, task)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+// DecodeImageProcessingTask reads a task from a message body. Bodies from
+// before envelopes were introduced, which are the bare task, decode as
+// version 0 with no ID.
+func DecodeImageProcessingTask(body []byte) (*Envelope, ImageProcessingTask, error) {
+	var task ImageProcessingTask
+
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, task, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if envelope.Type == "" && envelope.Payload == nil {
+		if err := json.Unmarshal(body, &task); err != nil {
+			return nil, task, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		return &Envelope{Type: TypeImageProcessingTask, Payload: body}, task, nil
+	}
+
+	if envelope.Type != TypeImageProcessingTask {
+		return &envelope, task, fmt.Errorf("%w: unexpected type %q", ErrInvalidMessage, envelope.Type)
+	}
+	if envelope.Version < 1 || envelope.Version > ImageProcessingTaskVersion {
+		return &envelope, task, fmt.Errorf("%w: %s version %d, this build reads up to %d",
+			ErrUnsupportedVersion, envelope.Type, envelope.Version, ImageProcessingTaskVersion)
+	}
+	if err := json.Unmarshal(envelope.Payload, &task); err != nil {
+		return &envelope, task, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return &envelope, task, nil
+}
+
+// Correlation returns the correlation ID for messages that follow from
+// this one: its own correlation ID, or its ID when it started the chain.
+func (e *Envelope) Correlation() string {
+	if e.CorrelationID != "" {
+		return e.CorrelationID
+	}
+	return e.ID
+}
@@ -0,0 +1,33 @@
+package messaging
+
+// Queues used for image processing.
+const (
+	ImageProcessingQueue = "image_processing"
+	ImageProcessingDLQ   = "image_processing_dlq"
+)
+
+// Headers carried by image tasks between attempts and onto the dead letter
+// queue. The message body is always the original task.
+const (
+	// HeaderAttempts counts the failed attempts so far.
+	HeaderAttempts = "x-attempts"
+	// HeaderError is the error of the last attempt.
+	HeaderError = "x-error"
+	// HeaderErrorClass groups errors by cause, see the ErrorClass values.
+	HeaderErrorClass = "x-error-class"
+	// HeaderFirstFailedAt and HeaderFailedAt are RFC 3339 timestamps of the
+	// first and the last failure.
+	HeaderFirstFailedAt = "x-first-failed-at"
+	HeaderFailedAt      = "x-failed-at"
+	// HeaderOriginalQueue is the queue the message was consumed from.
+	HeaderOriginalQueue = "x-original-queue"
+)
+
+// Error classes recorded in HeaderErrorClass.
+const (
+	ErrorClassInvalidTask        = "invalid_task"
+	ErrorClassUnsupportedVersion = "unsupported_version"
+	ErrorClassInvalidImage       = "invalid_image"
+	ErrorClassTimeout            = "timeout"
+	ErrorClassProcessing         = "processing"
+)
@@ -0,0 +1,470 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// ErrUnknownQueue is returned by MemoryBroker for queues it wasn't given.
+var ErrUnknownQueue = errors.New("queue not declared")
+
+// Broker backends the binaries can be configured with.
+const (
+	BrokerRabbitMQ = "rabbitmq"
+	BrokerMemory   = "memory"
+)
+
+// MemoryBroker is an in-process broker for tests and local runs without
+// RabbitMQ. It follows RabbitMQ's semantics for what this project uses:
+// publishing through the default exchange, prefetch, ack, nack with or
+// without requeue, and queues with a message TTL and dead-letter routing
+// key. Unlike RabbitMQ it rejects messages for undeclared queues instead of
+// dropping them, and nothing survives Close.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	queues  map[string]*memoryQueue
+	nextTag uint64
+	nextSeq uint64
+	// unacked holds every delivery awaiting ack by delivery tag.
+	unacked map[uint64]*memoryDelivery
+	closed  bool
+}
+
+type memoryQueue struct {
+	name string
+	// ttl and deadLetterTo come from the x-message-ttl and
+	// x-dead-letter-routing-key arguments.
+	ttl          time.Duration
+	deadLetterTo string
+
+	ready         []*memoryMessage
+	subscriptions []*memorySubscription
+	// next is the subscription that gets the next message, round robin.
+	next int
+}
+
+type memoryMessage struct {
+	seq         uint64
+	msg         amqp.Publishing
+	redelivered bool
+	expiry      *time.Timer
+}
+
+type memoryDelivery struct {
+	queue        *memoryQueue
+	subscription *memorySubscription
+	message      *memoryMessage
+}
+
+type memorySubscription struct {
+	broker     *MemoryBroker
+	queue      *memoryQueue
+	tag        string
+	prefetch   int
+	inFlight   int
+	deliveries chan amqp.Delivery
+	cancelled  bool
+}
+
+// NewMemoryBroker creates a broker with the topology declared.
+func NewMemoryBroker(topology []Queue) *MemoryBroker {
+	b := &MemoryBroker{
+		queues:  make(map[string]*memoryQueue),
+		unacked: make(map[uint64]*memoryDelivery),
+	}
+	for _, q := range topology {
+		b.declare(q)
+	}
+	return b
+}
+
+func (b *MemoryBroker) declare(q Queue) {
+	queue := &memoryQueue{name: q.Name}
+	if ttl, ok := q.Args["x-message-ttl"].(int64); ok {
+		queue.ttl = time.Duration(ttl) * time.Millisecond
+	}
+	if to, ok := q.Args["x-dead-letter-routing-key"].(string); ok {
+		queue.deadLetterTo = to
+	}
+	b.queues[q.Name] = queue
+}
+
+// Publish sends data to queue as a persistent JSON message.
+func (b *MemoryBroker) Publish(queue string, data []byte) error {
+	return b.PublishMessage(context.Background(), queue, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    uuid.New().String(),
+		Body:         data,
+	})
+}
+
+// PublishMessage appends msg to queue. Publishes are confirmed as soon as
+// they return.
+func (b *MemoryBroker) PublishMessage(ctx context.Context, queue string, msg amqp.Publishing) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	q, ok := b.queues[queue]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownQueue, queue)
+	}
+	b.enqueue(q, &memoryMessage{msg: msg})
+	return nil
+}
+
+// enqueue adds a new message to the back of q.
+func (b *MemoryBroker) enqueue(q *memoryQueue, m *memoryMessage) {
+	b.nextSeq++
+	m.seq = b.nextSeq
+	if q.ttl > 0 {
+		seq := m.seq
+		m.expiry = time.AfterFunc(q.ttl, func() { b.expire(q, seq) })
+	}
+	q.ready = append(q.ready, m)
+	b.dispatch(q)
+}
+
+// expire dead-letters a message whose TTL passed while it was waiting.
+func (b *MemoryBroker) expire(q *memoryQueue, seq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	for i, m := range q.ready {
+		if m.seq == seq {
+			q.ready = append(q.ready[:i], q.ready[i+1:]...)
+			b.deadLetter(q, m)
+			return
+		}
+	}
+}
+
+// deadLetter routes a rejected or expired message to the queue's dead
+// letter routing key, or drops it when the queue has none.
+func (b *MemoryBroker) deadLetter(q *memoryQueue, m *memoryMessage) {
+	target, ok := b.queues[q.deadLetterTo]
+	if q.deadLetterTo == "" || !ok {
+		return
+	}
+	b.enqueue(target, &memoryMessage{msg: m.msg})
+}
+
+// dispatch hands ready messages to subscriptions with room under their
+// prefetch. Sends never block because a subscription's channel holds
+// prefetch deliveries.
+func (b *MemoryBroker) dispatch(q *memoryQueue) {
+	for len(q.ready) > 0 {
+		s := q.nextSubscription()
+		if s == nil {
+			return
+		}
+
+		m := q.ready[0]
+		q.ready = q.ready[1:]
+		if m.expiry != nil {
+			m.expiry.Stop()
+		}
+
+		b.nextTag++
+		s.inFlight++
+		b.unacked[b.nextTag] = &memoryDelivery{queue: q, subscription: s, message: m}
+		s.deliveries <- amqp.Delivery{
+			Acknowledger: b,
+			Headers:      m.msg.Headers,
+			ContentType:  m.msg.ContentType,
+			DeliveryMode: m.msg.DeliveryMode,
+			MessageId:    m.msg.MessageId,
+			Timestamp:    m.msg.Timestamp,
+			ConsumerTag:  s.tag,
+			DeliveryTag:  b.nextTag,
+			Redelivered:  m.redelivered,
+			RoutingKey:   q.name,
+			Body:         m.msg.Body,
+		}
+	}
+}
+
+// nextSubscription picks the next subscription with room for a delivery.
+func (q *memoryQueue) nextSubscription() *memorySubscription {
+	for range q.subscriptions {
+		s := q.subscriptions[q.next%len(q.subscriptions)]
+		q.next++
+		if s.inFlight < s.prefetch {
+			return s
+		}
+	}
+	return nil
+}
+
+// Subscribe starts consuming queue with up to prefetch unacknowledged
+// deliveries; a prefetch below 1 counts as 1.
+func (b *MemoryBroker) Subscribe(queue, tag string, prefetch int) Subscription {
+	prefetch = max(prefetch, 1)
+	s := &memorySubscription{
+		broker:     b,
+		tag:        tag,
+		prefetch:   prefetch,
+		deliveries: make(chan amqp.Delivery, prefetch),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[queue]
+	if !ok || b.closed {
+		// RabbitMQ fails the channel; there is nothing to consume
+		s.cancelled = true
+		close(s.deliveries)
+		return s
+	}
+	s.queue = q
+	q.subscriptions = append(q.subscriptions, s)
+	b.dispatch(q)
+	return s
+}
+
+func (s *memorySubscription) Deliveries() <-chan amqp.Delivery {
+	return s.deliveries
+}
+
+// Cancel stops deliveries to the subscription. Messages it holds stay
+// unacknowledged until they are acked or rejected.
+func (s *memorySubscription) Cancel() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.cancel()
+	return nil
+}
+
+func (s *memorySubscription) cancel() {
+	if s.cancelled {
+		return
+	}
+	s.cancelled = true
+	close(s.deliveries)
+
+	q := s.queue
+	for i, other := range q.subscriptions {
+		if other == s {
+			q.subscriptions = append(q.subscriptions[:i], q.subscriptions[i+1:]...)
+			break
+		}
+	}
+}
+
+// Ack implements amqp.Acknowledger.
+func (b *MemoryBroker) Ack(tag uint64, multiple bool) error {
+	return b.settle(tag, multiple, func(d *memoryDelivery) {})
+}
+
+// Nack implements amqp.Acknowledger. Requeued messages go back to the front
+// of their queue marked as redelivered; the others are dead-lettered.
+func (b *MemoryBroker) Nack(tag uint64, multiple bool, requeue bool) error {
+	return b.settle(tag, multiple, func(d *memoryDelivery) {
+		if !requeue {
+			b.deadLetter(d.queue, d.message)
+			return
+		}
+		d.message.redelivered = true
+		d.queue.ready = append([]*memoryMessage{d.message}, d.queue.ready...)
+	})
+}
+
+// Reject implements amqp.Acknowledger.
+func (b *MemoryBroker) Reject(tag uint64, requeue bool) error {
+	return b.Nack(tag, false, requeue)
+}
+
+// settle applies fn to the delivery with tag, and with multiple to every
+// earlier delivery of the same subscription, then refills the queues.
+func (b *MemoryBroker) settle(tag uint64, multiple bool, fn func(*memoryDelivery)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	d, ok := b.unacked[tag]
+	if !ok {
+		return fmt.Errorf("unknown delivery tag %d", tag)
+	}
+
+	tags := []uint64{tag}
+	if multiple {
+		for other, od := range b.unacked {
+			if other < tag && od.subscription == d.subscription {
+				tags = append(tags, other)
+			}
+		}
+	}
+
+	queues := make(map[*memoryQueue]bool)
+	for _, t := range tags {
+		d := b.unacked[t]
+		delete(b.unacked, t)
+		d.subscription.inFlight--
+		fn(d)
+		queues[d.queue] = true
+	}
+	for q := range queues {
+		b.dispatch(q)
+	}
+	return nil
+}
+
+// Messages returns copies of the messages waiting on queue, oldest first.
+// Delivered but unacknowledged messages aren't included.
+func (b *MemoryBroker) Messages(queue string) []amqp.Publishing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil
+	}
+	messages := make([]amqp.Publishing, len(q.ready))
+	for i, m := range q.ready {
+		messages[i] = m.msg
+	}
+	return messages
+}
+
+// Close ends every subscription and discards all messages.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	for _, q := range b.queues {
+		for _, m := range q.ready {
+			if m.expiry != nil {
+				m.expiry.Stop()
+			}
+		}
+		for len(q.subscriptions) > 0 {
+			q.subscriptions[0].cancel()
+		}
+	}
+	return nil
+}
+
+// MemoryDeadLetterQueue inspects and drains a dead letter queue of a
+// MemoryBroker, like DeadLetterQueue does on RabbitMQ.
+type MemoryDeadLetterQueue struct {
+	broker      *MemoryBroker
+	queue       string
+	replayQueue string
+}
+
+// DeadLetterQueue returns the dead letter queue queue, whose replayed
+// messages go to replayQueue.
+func (b *MemoryBroker) DeadLetterQueue(queue, replayQueue string) *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{broker: b, queue: queue, replayQueue: replayQueue}
+}
+
+// List returns up to limit matching messages without removing them. A
+// limit of 0 returns every match.
+func (q *MemoryDeadLetterQueue) List(ctx context.Context, filter DeadLetterFilter, limit int) ([]DeadLetter, error) {
+	var matches []DeadLetter
+	err := q.walk(func(m DeadLetter) (bool, error) {
+		if filter.Matches(m) && (limit == 0 || len(matches) < limit) {
+			matches = append(matches, m)
+		}
+		return false, nil
+	})
+	return matches, err
+}
+
+// Purge deletes the matching messages and returns how many were deleted.
+func (q *MemoryDeadLetterQueue) Purge(ctx context.Context, filter DeadLetterFilter) (int, error) {
+	purged := 0
+	err := q.walk(func(m DeadLetter) (bool, error) {
+		if !filter.Matches(m) {
+			return false, nil
+		}
+		purged++
+		return true, nil
+	})
+	return purged, err
+}
+
+// Replay moves the matching replayable messages to the replay queue with a
+// clean attempt count.
+func (q *MemoryDeadLetterQueue) Replay(ctx context.Context, filter DeadLetterFilter) ([]DeadLetter, error) {
+	b := q.broker
+	var replayed []DeadLetter
+	err := q.walk(func(m DeadLetter) (bool, error) {
+		if !filter.Matches(m) || !m.Replayable() {
+			return false, nil
+		}
+		target, ok := b.queues[q.replayQueue]
+		if !ok {
+			return false, fmt.Errorf("%w: %s", ErrUnknownQueue, q.replayQueue)
+		}
+		b.enqueue(target, &memoryMessage{msg: amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    m.ID,
+			Body:         m.Body,
+		}})
+		replayed = append(replayed, m)
+		return true, nil
+	})
+	return replayed, err
+}
+
+// walk calls fn with each message waiting on the queue and removes the
+// ones fn returns true for. The broker is locked throughout, so the walk
+// sees every message once.
+func (q *MemoryDeadLetterQueue) walk(fn func(DeadLetter) (bool, error)) error {
+	b := q.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	dlq, ok := b.queues[q.queue]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownQueue, q.queue)
+	}
+
+	kept := dlq.ready[:0]
+	var err error
+	for i, m := range dlq.ready {
+		var remove bool
+		remove, err = fn(parseDeadLetter(amqp.Delivery{
+			Headers:     m.msg.Headers,
+			ContentType: m.msg.ContentType,
+			MessageId:   m.msg.MessageId,
+			Body:        m.msg.Body,
+		}))
+		if err != nil {
+			kept = append(kept, dlq.ready[i:]...)
+			break
+		}
+		if remove {
+			if m.expiry != nil {
+				m.expiry.Stop()
+			}
+			continue
+		}
+		kept = append(kept, m)
+	}
+	dlq.ready = kept
+	return err
+}
@@ -1,6 +1,14 @@
 package messaging
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 )
 
@@ -8,56 +16,346 @@ type Publisher interface {
 	Publish(queue string, data []byte) error
 }
 
+var (
+	// ErrClosed is returned once the client has been closed.
+	ErrClosed = errors.New("rabbitmq client closed")
+	// ErrNotConfirmed is returned when the broker rejects a message or
+	// doesn't confirm it within the confirm timeout.
+	ErrNotConfirmed = errors.New("broker did not confirm the message")
+)
+
+// Queue is a durable queue declared by the client whenever it connects.
+type Queue struct {
+	Name string
+	Args amqp.Table
+}
+
+// Config tunes the client. Zero values fall back to the defaults below.
+type Config struct {
+	// Topology is declared on connecting and again after every reconnect,
+	// so publishing never has to declare queues.
+	Topology []Queue
+	// ConfirmTimeout bounds a publish, including waiting for a lost
+	// connection to come back and for the broker's confirmation.
+	ConfirmTimeout time.Duration
+	// ChannelPoolSize is the number of idle publishing channels kept open.
+	ChannelPoolSize int
+	// ReconnectBackoff is the wait before the first reconnect attempt,
+	// doubling after each failed attempt up to MaxReconnectBackoff.
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+}
+
+const (
+	defaultConfirmTimeout      = 5 * time.Second
+	defaultChannelPoolSize     = 8
+	defaultReconnectBackoff    = 500 * time.Millisecond
+	defaultMaxReconnectBackoff = 30 * time.Second
+)
+
+// RabbitMQClient keeps a connection to RabbitMQ open, reconnecting with
+// backoff whenever it is lost. Publishes go out on a pool of channels in
+// confirm mode and only succeed once the broker has confirmed the message.
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	url string
+	cfg Config
+
+	mu sync.Mutex
+	// conn is nil while reconnecting; ready is closed once it is set.
+	conn  *amqp.Connection
+	ready chan struct{}
+
+	pool      chan *confirmChannel
+	closing   chan struct{}
+	closeOnce sync.Once
 }
 
-func NewRabbitMQClient(url string) (*RabbitMQClient, error) {
-	conn, err := amqp.Dial(url)
+// confirmChannel is a pooled publishing channel in confirm mode.
+type confirmChannel struct {
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+	closed   chan *amqp.Error
+}
+
+// NewRabbitMQClient connects to url and declares cfg.Topology. Only the
+// first connection has to succeed; later ones are retried until Close.
+func NewRabbitMQClient(url string, cfg Config) (*RabbitMQClient, error) {
+	if cfg.ConfirmTimeout <= 0 {
+		cfg.ConfirmTimeout = defaultConfirmTimeout
+	}
+	if cfg.ChannelPoolSize <= 0 {
+		cfg.ChannelPoolSize = defaultChannelPoolSize
+	}
+	if cfg.ReconnectBackoff <= 0 {
+		cfg.ReconnectBackoff = defaultReconnectBackoff
+	}
+	if cfg.MaxReconnectBackoff <= 0 {
+		cfg.MaxReconnectBackoff = defaultMaxReconnectBackoff
+	}
+
+	c := &RabbitMQClient{
+		url:     url,
+		cfg:     cfg,
+		ready:   make(chan struct{}),
+		pool:    make(chan *confirmChannel, cfg.ChannelPoolSize),
+		closing: make(chan struct{}),
+	}
+
+	conn, err := c.connect()
 	if err != nil {
 		return nil, err
 	}
+	c.setConnection(conn)
+	return c, nil
+}
 
-	ch, err := conn.Channel()
+func (c *RabbitMQClient) connect() (*amqp.Connection, error) {
+	conn, err := amqp.Dial(c.url)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.declareTopology(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
 
-	return &RabbitMQClient{
-		conn:    conn,
-		channel: ch,
-	}, nil
+func (c *RabbitMQClient) declareTopology(conn *amqp.Connection) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for _, q := range c.cfg.Topology {
+		_, err := ch.QueueDeclare(
+			q.Name,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			q.Args,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+func (c *RabbitMQClient) setConnection(conn *amqp.Connection) {
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	c.mu.Lock()
+	c.conn = conn
+	close(c.ready)
+	c.mu.Unlock()
+
+	go c.watch(closed)
+}
+
+// watch reconnects once the connection closes, unless the client is being
+// closed.
+func (c *RabbitMQClient) watch(closed chan *amqp.Error) {
+	select {
+	case <-c.closing:
+		return
+	case err := <-closed:
+		// Connections closed on purpose report no error
+		if err == nil {
+			return
+		}
+		log.Printf("RabbitMQ connection lost, reconnecting: %v", err)
+	}
+
+	c.mu.Lock()
+	c.conn = nil
+	c.ready = make(chan struct{})
+	c.mu.Unlock()
+
+	delay := c.cfg.ReconnectBackoff
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-timer.C:
+		}
+
+		conn, err := c.connect()
+		if err == nil {
+			log.Printf("RabbitMQ connection restored")
+			c.setConnection(conn)
+			return
+		}
+
+		delay = min(2*delay, c.cfg.MaxReconnectBackoff)
+		log.Printf("Failed to reconnect to RabbitMQ, retrying in %s: %v", delay, err)
+		timer.Reset(delay)
+	}
+}
+
+// connection returns the open connection, waiting for a reconnect when
+// there is none.
+func (c *RabbitMQClient) connection(ctx context.Context) (*amqp.Connection, error) {
+	for {
+		c.mu.Lock()
+		conn, ready := c.conn, c.ready
+		c.mu.Unlock()
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-ready:
+		case <-c.closing:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Publish sends data to queue as a persistent JSON message and waits for
+// the broker to confirm it.
 func (c *RabbitMQClient) Publish(queue string, data []byte) error {
-	_, err := c.channel.QueueDeclare(
-		queue,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	return c.PublishMessage(context.Background(), queue, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    uuid.New().String(),
+		Body:         data,
+	})
+}
+
+// PublishMessage sends msg to queue through the default exchange and waits
+// for the broker to confirm it, for at most the confirm timeout. The queue
+// must be part of the topology: the broker confirms messages it drops for
+// lack of a queue.
+func (c *RabbitMQClient) PublishMessage(ctx context.Context, queue string, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.ConfirmTimeout)
+	defer cancel()
+
+	pc, err := c.channel(ctx)
 	if err != nil {
 		return err
 	}
 
-	return c.channel.Publish(
-		"",
-		queue,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        data,
-		},
+	err = pc.ch.Publish(
+		"",    // exchange
+		queue, // routing key
+		false, // mandatory
+		false, // immediate
+		msg,
 	)
+	if err != nil {
+		pc.ch.Close()
+		return err
+	}
+
+	select {
+	case confirm, ok := <-pc.confirms:
+		if !ok {
+			return fmt.Errorf("%w: channel closed", ErrNotConfirmed)
+		}
+		c.release(pc)
+		if !confirm.Ack {
+			return fmt.Errorf("%w: rejected by the broker", ErrNotConfirmed)
+		}
+		return nil
+	case <-ctx.Done():
+		// A late confirmation would be taken for the next message's, so the
+		// channel can't be reused
+		pc.ch.Close()
+		return fmt.Errorf("%w: %v", ErrNotConfirmed, ctx.Err())
+	}
+}
+
+// channel takes an idle channel from the pool or opens a new one.
+func (c *RabbitMQClient) channel(ctx context.Context) (*confirmChannel, error) {
+	for {
+		select {
+		case pc := <-c.pool:
+			if pc.usable() {
+				return pc, nil
+			}
+			pc.ch.Close()
+			continue
+		default:
+		}
+		break
+	}
+
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	return &confirmChannel{
+		conn:     conn,
+		ch:       ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		closed:   ch.NotifyClose(make(chan *amqp.Error, 1)),
+	}, nil
 }
 
+// release returns a channel to the pool, or closes it when the pool is
+// full or the client closed.
+func (c *RabbitMQClient) release(pc *confirmChannel) {
+	select {
+	case <-c.closing:
+		pc.ch.Close()
+		return
+	default:
+	}
+
+	select {
+	case c.pool <- pc:
+	default:
+		pc.ch.Close()
+	}
+}
+
+func (pc *confirmChannel) usable() bool {
+	select {
+	case <-pc.closed:
+		return false
+	default:
+		return !pc.conn.IsClosed()
+	}
+}
+
+// Close stops reconnecting and closes the connection.
 func (c *RabbitMQClient) Close() error {
-	if err := c.channel.Close(); err != nil {
-		return err
+	c.closeOnce.Do(func() { close(c.closing) })
+
+	for {
+		select {
+		case pc := <-c.pool:
+			pc.ch.Close()
+			continue
+		default:
+		}
+		break
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.mu.Unlock()
+
+	if conn == nil || conn.IsClosed() {
+		return nil
 	}
-	return c.conn.Close()
+	return conn.Close()
 }
@@ -0,0 +1,253 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var errCancelled = errors.New("subscription cancelled")
+
+// Subscription is a consumer of a queue.
+type Subscription interface {
+	// Deliveries hands out messages until the subscription is cancelled or
+	// its broker closed. Each must be acknowledged or rejected.
+	Deliveries() <-chan amqp.Delivery
+	// Cancel stops consuming. Deliveries already handed to the consumer
+	// are still delivered before the channel closes, and the broker
+	// resources of the subscription are released once all of them were
+	// acknowledged or rejected.
+	Cancel() error
+}
+
+// rabbitSubscription consumes a queue and keeps consuming it across
+// reconnects. Deliveries from a lost channel can't be acknowledged any
+// more; the broker redelivers them.
+type rabbitSubscription struct {
+	client     *RabbitMQClient
+	queue      string
+	tag        string
+	prefetch   int
+	deliveries chan amqp.Delivery
+
+	ctx  context.Context
+	stop context.CancelFunc
+
+	mu        sync.Mutex
+	ch        *amqp.Channel
+	cancelled bool
+}
+
+// Subscribe starts consuming queue with up to prefetch unacknowledged
+// deliveries. The deliveries channel closes after Cancel or Close.
+func (c *RabbitMQClient) Subscribe(queue, tag string, prefetch int) Subscription {
+	ctx, stop := context.WithCancel(context.Background())
+	s := &rabbitSubscription{
+		client:     c,
+		queue:      queue,
+		tag:        tag,
+		prefetch:   prefetch,
+		deliveries: make(chan amqp.Delivery),
+		ctx:        ctx,
+		stop:       stop,
+	}
+	go s.run()
+	return s
+}
+
+func (s *rabbitSubscription) Deliveries() <-chan amqp.Delivery {
+	return s.deliveries
+}
+
+func (s *rabbitSubscription) Cancel() error {
+	s.mu.Lock()
+	s.cancelled = true
+	ch := s.ch
+	s.mu.Unlock()
+
+	s.stop()
+	if ch == nil {
+		return nil
+	}
+	return ch.Cancel(s.tag, false)
+}
+
+func (s *rabbitSubscription) run() {
+	var closeOnce sync.Once
+	closeDeliveries := func() { closeOnce.Do(func() { close(s.deliveries) }) }
+	defer closeDeliveries()
+
+	delay := s.client.cfg.ReconnectBackoff
+	for {
+		msgs, acks, err := s.consume()
+		if errors.Is(err, errCancelled) || errors.Is(err, ErrClosed) || errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to consume %s, retrying in %s: %v", s.queue, delay, err)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(2*delay, s.client.cfg.MaxReconnectBackoff)
+			continue
+		}
+		delay = s.client.cfg.ReconnectBackoff
+
+		for d := range msgs {
+			s.deliveries <- acks.track(d)
+		}
+
+		s.mu.Lock()
+		s.ch = nil
+		cancelled := s.cancelled
+		s.mu.Unlock()
+		if cancelled {
+			// Acknowledgements only reach the broker on the channel the
+			// deliveries came from, so it is closed once they are settled
+			closeDeliveries()
+			acks.wait()
+			acks.ch.Close()
+			return
+		}
+		log.Printf("Lost the channel consuming %s, resubscribing", s.queue)
+	}
+}
+
+// consume opens a channel on the current connection and starts consuming.
+func (s *rabbitSubscription) consume() (<-chan amqp.Delivery, *channelAcks, error) {
+	conn, err := s.client.connection(s.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancelled {
+		return nil, nil, errCancelled
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = ch.Qos(
+		s.prefetch, // prefetch count
+		0,          // prefetch size
+		false,      // global
+	)
+	if err != nil {
+		ch.Close()
+		return nil, nil, err
+	}
+
+	msgs, err := ch.Consume(
+		s.queue, // queue
+		s.tag,   // consumer
+		false,   // auto-ack
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, nil, err
+	}
+
+	s.ch = ch
+	return msgs, newChannelAcks(ch), nil
+}
+
+// channelAcks acknowledges deliveries on the channel they came from and
+// keeps track of the ones the consumer hasn't settled yet.
+type channelAcks struct {
+	ch     *amqp.Channel
+	closed chan *amqp.Error
+
+	mu        sync.Mutex
+	unsettled map[uint64]bool
+	// idle is signalled whenever the last unsettled delivery is settled
+	idle chan struct{}
+}
+
+func newChannelAcks(ch *amqp.Channel) *channelAcks {
+	return &channelAcks{
+		ch:        ch,
+		closed:    ch.NotifyClose(make(chan *amqp.Error, 1)),
+		unsettled: make(map[uint64]bool),
+		idle:      make(chan struct{}, 1),
+	}
+}
+
+// track makes d settle through a.
+func (a *channelAcks) track(d amqp.Delivery) amqp.Delivery {
+	a.mu.Lock()
+	a.unsettled[d.DeliveryTag] = true
+	a.mu.Unlock()
+
+	d.Acknowledger = a
+	return d
+}
+
+// Ack implements amqp.Acknowledger.
+func (a *channelAcks) Ack(tag uint64, multiple bool) error {
+	defer a.settle(tag, multiple)
+	return a.ch.Ack(tag, multiple)
+}
+
+// Nack implements amqp.Acknowledger.
+func (a *channelAcks) Nack(tag uint64, multiple bool, requeue bool) error {
+	defer a.settle(tag, multiple)
+	return a.ch.Nack(tag, multiple, requeue)
+}
+
+// Reject implements amqp.Acknowledger.
+func (a *channelAcks) Reject(tag uint64, requeue bool) error {
+	defer a.settle(tag, false)
+	return a.ch.Reject(tag, requeue)
+}
+
+func (a *channelAcks) settle(tag uint64, multiple bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.unsettled, tag)
+	if multiple {
+		for t := range a.unsettled {
+			if t < tag {
+				delete(a.unsettled, t)
+			}
+		}
+	}
+	if len(a.unsettled) == 0 {
+		select {
+		case a.idle <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wait returns once every tracked delivery was settled or the channel
+// closed, which settles them on the broker's side.
+func (a *channelAcks) wait() {
+	for {
+		a.mu.Lock()
+		n := len(a.unsettled)
+		a.mu.Unlock()
+		if n == 0 {
+			return
+		}
+
+		select {
+		case <-a.idle:
+		case <-a.closed:
+			return
+		}
+	}
+}
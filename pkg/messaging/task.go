@@ -1,5 +1,8 @@
 package messaging
 
+// ImageProcessingTask asks the image processor to generate variants of
+// Images, which may be any subset of the product's images. It travels as
+// the payload of an Envelope of type TypeImageProcessingTask.
 type ImageProcessingTask struct {
 	ProductID uint     `json:"product_id"`
 	Images    []string `json:"images"`
@@ -0,0 +1,40 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ImageProcessingTopology returns the image processing queues: the task
+// queue, its dead letter queue and a delay queue per retry delay.
+func ImageProcessingTopology(retryDelays []time.Duration) []Queue {
+	topology := []Queue{
+		{Name: ImageProcessingQueue},
+		{Name: ImageProcessingDLQ},
+	}
+	for _, delay := range retryDelays {
+		topology = append(topology, RetryQueue(ImageProcessingQueue, delay))
+	}
+	return topology
+}
+
+// RetryQueue is the delay queue for queue. Messages wait there until their
+// TTL expires and are then dead-lettered back onto queue.
+func RetryQueue(queue string, delay time.Duration) Queue {
+	return Queue{
+		Name: RetryQueueName(queue, delay),
+		Args: amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		},
+	}
+}
+
+// RetryQueueName names the queue that holds messages for delay before
+// dead-lettering them back to queue.
+func RetryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", queue, delay.Milliseconds())
+}
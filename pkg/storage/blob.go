@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Blob is an object store addressed by slash-separated keys. Objects are
+// publicly readable under URL(key); PresignGet hands out time-limited links
+// where the backend supports access control.
+type Blob interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	URL(key string) string
+}
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+// Config selects and configures a Blob backend.
+type Config struct {
+	Backend string
+	S3      S3Config
+	Local   LocalConfig
+}
+
+// New builds the Blob backend named by cfg.Backend. An empty backend
+// defaults to S3.
+func New(cfg Config) (Blob, error) {
+	switch cfg.Backend {
+	case BackendS3, "":
+		return NewS3ClientFromConfig(cfg.S3)
+	case BackendLocal:
+		return NewLocalStore(cfg.Local)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// KeyFromURL returns the key of an object in b from its public URL, or false
+// if the URL does not belong to b.
+func KeyFromURL(b Blob, url string) (string, bool) {
+	prefix := b.URL("")
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, prefix)
+	return key, key != ""
+}
@@ -3,24 +3,74 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"io"
 )
 
+// S3Config configures an S3 or S3-compatible (e.g. MinIO) backend. Endpoint
+// and ForcePathStyle are only needed for S3-compatible servers. PublicURL is
+// the base URL objects are reachable under; it defaults to the AWS virtual
+// hosted-style bucket URL.
+type S3Config struct {
+	Region         string
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	Endpoint       string
+	PublicURL      string
+	ForcePathStyle bool
+}
+
 type S3Client struct {
-	client *s3.S3
-	bucket string
+	client  *s3.S3
+	bucket  string
+	baseURL string
 }
 
+var _ Blob = (*S3Client)(nil)
+
 func NewS3Client(s3Client *s3.S3, bucket string) *S3Client {
 	return &S3Client{
-		client: s3Client,
-		bucket: bucket,
+		client:  s3Client,
+		bucket:  bucket,
+		baseURL: "https://" + bucket + ".s3.amazonaws.com",
+	}
+}
+
+func NewS3ClientFromConfig(cfg S3Config) (*S3Client, error) {
+	awsCfg := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.ForcePathStyle),
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	if cfg.AccessKey != "" {
+		awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, "")
+	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	client := NewS3Client(s3.New(sess), cfg.Bucket)
+	if cfg.PublicURL != "" {
+		client.baseURL = strings.TrimSuffix(cfg.PublicURL, "/")
 	}
+	return client, nil
 }
 
-func (c *S3Client) UploadFile(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+func (c *S3Client) Put(ctx context.Context, key string, data []byte, contentType string) error {
 	input := &s3.PutObjectInput{
 		Bucket:      aws.String(c.bucket),
 		Key:         aws.String(key),
@@ -28,35 +78,95 @@ func (c *S3Client) UploadFile(ctx context.Context, key string, data []byte, cont
 		ContentType: aws.String(contentType),
 	}
 
-	_, err := c.client.PutObject(input)
+	_, err := c.client.PutObjectWithContext(ctx, input)
+	return err
+}
+
+func (c *S3Client) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	}
+
+	result, err := c.client.GetObjectWithContext(ctx, input)
 	if err != nil {
-		return "", err
+		return nil, mapS3Error(err)
+	}
+	return result.Body, nil
+}
+
+func (c *S3Client) Delete(ctx context.Context, key string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
 	}
 
-	return "https://" + c.bucket + ".s3.amazonaws.com/" + key, nil
+	_, err := c.client.DeleteObjectWithContext(ctx, input)
+	return err
 }
 
-func (c *S3Client) DownloadFile(ctx context.Context, key string) ([]byte, error) {
-	input := &s3.GetObjectInput{
+func (c *S3Client) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	input := &s3.HeadObjectInput{
 		Bucket: aws.String(c.bucket),
 		Key:    aws.String(key),
 	}
 
-	result, err := c.client.GetObject(input)
+	result, err := c.client.HeadObjectWithContext(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, mapS3Error(err)
 	}
-	defer result.Body.Close()
 
-	return io.ReadAll(result.Body)
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.Int64Value(result.ContentLength),
+		ContentType:  aws.StringValue(result.ContentType),
+		LastModified: aws.TimeValue(result.LastModified),
+	}, nil
 }
 
-func (c *S3Client) DeleteFile(ctx context.Context, key string) error {
-	input := &s3.DeleteObjectInput{
+func (c *S3Client) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(c.bucket),
-		Key:    aws.String(key),
+		Prefix: aws.String(prefix),
 	}
 
-	_, err := c.client.DeleteObject(input)
+	var objects []ObjectInfo
+	err := c.client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.StringValue(obj.Key),
+				Size:         aws.Int64Value(obj.Size),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (c *S3Client) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	req, _ := c.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expires)
+}
+
+func (c *S3Client) URL(key string) string {
+	return c.baseURL + "/" + key
+}
+
+func mapS3Error(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return fmt.Errorf("%w: %s", ErrNotFound, aerr.Message())
+		}
+	}
 	return err
 }